@@ -0,0 +1,220 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An Aggregate is a group of errors with the same key.
+type Aggregate struct {
+	Key    string
+	Count  int
+	First  time.Time // when the first error was added
+	Last   time.Time // when the latest error was added
+	Sample error     // the latest error
+
+	// Severity is the severity the group was last escalated to, or
+	// SeverityUnset.
+	Severity Severity
+
+	recent []time.Time // times of errors within the longest rule window
+}
+
+// An EscalationRule escalates a group of errors once more than Count of
+// them occur within Window. Its Severity is the severity to escalate to;
+// if unset, the group is escalated one level above SeverityOf its latest
+// error.
+type EscalationRule struct {
+	Count    int
+	Window   time.Duration
+	Severity Severity
+}
+
+// Aggregator counts errors grouped by a key, for summaries of what went
+// wrong over a period, and can escalate groups that occur too often. It
+// is safe for concurrent use and implements Reporter.
+type Aggregator struct {
+	key func(error) string
+
+	mu       sync.Mutex
+	groups   map[string]*Aggregate
+	reporter Reporter
+	rules    []EscalationRule
+	window   time.Duration // longest rule window
+}
+
+// NewAggregator returns an Aggregator that groups errors by key, such as
+// FingerprintKey or SignatureKey(3). A nil key means FingerprintKey.
+func NewAggregator(key func(error) string) *Aggregator {
+	if key == nil {
+		key = FingerprintKey
+	}
+	return &Aggregator{key: key, groups: map[string]*Aggregate{}}
+}
+
+// FingerprintKey groups errors by the Fingerprint of the outermost
+// StackableError in their chain, or by message if there is none. A nil
+// error has the empty key.
+func FingerprintKey(err error) string {
+	if err == nil {
+		return ""
+	}
+	var e *StackableError
+	if errors.As(err, &e) && e != nil {
+		return e.Fingerprint()
+	}
+	return err.Error()
+}
+
+// SignatureKey groups errors by their Signature with n frames, so errors
+// raised by the same code group together whatever their messages. Errors
+// without application frames are grouped by message. A nil error has the
+// empty key.
+func SignatureKey(n int) func(error) string {
+	return func(err error) string {
+		if err == nil {
+			return ""
+		}
+		if sig := Signature(err, n); sig != "" {
+			return sig
+		}
+		return err.Error()
+	}
+}
+
+// Escalate sets rules for surfacing slow-burn problems: when a group
+// matches a rule whose severity is higher than the group's current one,
+// its latest error is copied with that severity and an "occurrences"
+// field and reported again through r. Escalation only ever raises a
+// group's severity, so each level is reported once until Reset. If r is
+// nil, groups are still escalated, as their Severity shows, but nothing
+// is reported.
+func (a *Aggregator) Escalate(r Reporter, rules ...EscalationRule) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reporter, a.rules, a.window = r, rules, 0
+	for _, rule := range rules {
+		if rule.Window > a.window {
+			a.window = rule.Window
+		}
+	}
+}
+
+// Add counts err in its group, applies the escalation rules and returns
+// the group's state. Errors on the global ignore list (see Ignored) are
+// not counted, and Add returns a zero Aggregate for them.
+func (a *Aggregator) Add(err error) Aggregate {
+	if Ignored(err) {
+		return Aggregate{}
+	}
+	key := a.key(err)
+	t := now()
+
+	a.mu.Lock()
+	group := a.groups[key]
+	if group == nil {
+		group = &Aggregate{Key: key, First: t}
+		a.groups[key] = group
+	}
+	group.Count++
+	group.Last = t
+	group.Sample = err
+	escalated, occurrences := a.escalate(group, t)
+	state, reporter := *group, a.reporter
+	a.mu.Unlock()
+
+	if escalated != SeverityUnset && reporter != nil {
+		reporter.Report(context.Background(), escalate(err, escalated, occurrences))
+	}
+	state.recent = nil
+	return state
+}
+
+// escalate records an occurrence of group at t and returns the severity
+// the group is escalated to, if any, along with the number of occurrences
+// within the matching rule's window.
+func (a *Aggregator) escalate(group *Aggregate, t time.Time) (Severity, int) {
+	if len(a.rules) == 0 {
+		return SeverityUnset, 0
+	}
+	recent := group.recent[:0]
+	for _, seen := range group.recent {
+		if t.Sub(seen) < a.window {
+			recent = append(recent, seen)
+		}
+	}
+	group.recent = append(recent, t)
+
+	for _, rule := range a.rules {
+		n := 0
+		for _, seen := range group.recent {
+			if t.Sub(seen) < rule.Window {
+				n++
+			}
+		}
+		if n <= rule.Count {
+			continue
+		}
+		severity := rule.Severity
+		if severity == SeverityUnset {
+			severity = SeverityOf(group.Sample)
+			if severity < SeverityCritical {
+				severity++
+			}
+		}
+		if severity > group.Severity {
+			group.Severity = severity
+			return severity, n
+		}
+	}
+	return SeverityUnset, 0
+}
+
+// escalate returns a copy of err with the given severity. It is built
+// directly rather than through Wrap, so the copy is not published again
+// to subscribers or run through the processors.
+func escalate(err error, severity Severity, occurrences int) *StackableError {
+	e, ok := err.(*StackableError)
+	if ok {
+		e = e.clone()
+	} else {
+		e = &StackableError{Err: err, frames: newFrameCache([]StackFrame{}), timestamp: now()}
+	}
+	e.Severity = severity
+	return e.WithField("occurrences", occurrences)
+}
+
+// Report implements Reporter by adding err.
+func (a *Aggregator) Report(ctx context.Context, err error) {
+	a.Add(err)
+}
+
+// Aggregates returns the groups, largest first.
+func (a *Aggregator) Aggregates() []Aggregate {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	groups := make([]Aggregate, 0, len(a.groups))
+	for _, group := range a.groups {
+		g := *group
+		g.recent = nil
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// Reset forgets all groups.
+func (a *Aggregator) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.groups = map[string]*Aggregate{}
+}
@@ -0,0 +1,104 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAggregatorEscalate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	SetNow(func() time.Time { return clock })
+	defer SetNow(nil)
+
+	var reported []*StackableError
+	a := NewAggregator(nil)
+	a.Escalate(ReporterFunc(func(ctx context.Context, err error) {
+		reported = append(reported, err.(*StackableError))
+	}),
+		EscalationRule{Count: 2, Window: time.Minute},
+		EscalationRule{Count: 4, Window: 10 * time.Second, Severity: SeverityCritical},
+	)
+
+	fail := func() error { return E("net.Dial", IO, errors.New("refused")) }
+	for _, offset := range []int{0, 1, 2, 30, 31, 32, 33, 34, 200} {
+		clock = start.Add(time.Duration(offset) * time.Second)
+		a.Add(fail())
+	}
+
+	expected := []struct {
+		severity    Severity
+		occurrences int
+	}{
+		{SeverityError, 3},
+		{SeverityCritical, 5},
+	}
+	if len(reported) != len(expected) {
+		t.Fatalf("expected %d escalations, got %d", len(expected), len(reported))
+	}
+	for i, e := range expected {
+		if reported[i].Severity != e.severity {
+			t.Errorf("escalation %d: expected severity %v, got %v", i, e.severity, reported[i].Severity)
+		}
+		if n, _ := reported[i].Field("occurrences"); n != e.occurrences {
+			t.Errorf("escalation %d: expected %d occurrences, got %v", i, e.occurrences, n)
+		}
+	}
+
+	groups := a.Aggregates()
+	if len(groups) != 1 || groups[0].Count != 9 || groups[0].Severity != SeverityCritical {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+}
+
+func TestAggregatorEscalateNilReporter(t *testing.T) {
+	a := NewAggregator(nil)
+	a.Escalate(nil, EscalationRule{Count: 1, Window: time.Minute, Severity: SeverityCritical})
+
+	var state Aggregate
+	for i := 0; i < 3; i++ {
+		state = a.Add(errors.New("boom"))
+	}
+	if state.Severity != SeverityCritical {
+		t.Errorf("expected the group to be escalated to critical, got %v", state.Severity)
+	}
+}
+
+func TestAggregatorEscalatePlainError(t *testing.T) {
+	var reported []*StackableError
+	a := NewAggregator(nil)
+	a.Escalate(ReporterFunc(func(ctx context.Context, err error) {
+		reported = append(reported, err.(*StackableError))
+	}), EscalationRule{Count: 1, Window: time.Minute, Severity: SeverityCritical})
+
+	s := Subscribe(SubscribeOptions{})
+	defer s.Close()
+	base := errors.New("boom")
+	a.Add(base)
+	a.Add(base)
+
+	if len(reported) != 1 || reported[0].Severity != SeverityCritical || !errors.Is(reported[0], base) {
+		t.Fatalf("expected one critical copy of the error, got %v", reported)
+	}
+	if occurrences, _ := reported[0].Field("occurrences"); occurrences != 2 {
+		t.Errorf("expected 2 occurrences, got %v", occurrences)
+	}
+	select {
+	case err := <-s.C:
+		t.Errorf("the escalated copy was published: %v", err)
+	default:
+	}
+}
+
+func TestKeysOfNil(t *testing.T) {
+	for name, key := range map[string]func(error) string{
+		"FingerprintKey": FingerprintKey,
+		"SignatureKey":   SignatureKey(3),
+	} {
+		if k := key(nil); k != "" {
+			t.Errorf("%s: expected the empty key, got %q", name, k)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package errgo
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+)
+
+// frameNote is a note attached to the frame of the function that called
+// AnnotateFrame.
+type frameNote struct {
+	function string
+	file     string
+	line     int
+	note     string
+}
+
+func (n frameNote) matches(frame StackFrame) bool {
+	return frame.File == n.file && frame.Package+"."+frame.FunctionName == n.function
+}
+
+// AnnotateFrame attaches a note, such as "retry attempt 3", to the frame of
+// the function calling it, and returns the error. Stack() and StackTrace()
+// print the note below that frame, or after the stack if the frame is not
+// part of it. If the error is frozen, a copy with the note is returned
+// instead.
+func (err *StackableError) AnnotateFrame(note string) *StackableError {
+	if err.frozen {
+		err = err.clone()
+	}
+	n := frameNote{note: note}
+	if pc, file, line, ok := runtime.Caller(1); ok {
+		n.file, n.line = file, line
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			n.function = fn.Name()
+		}
+	}
+	err.annotations = append(err.annotations, n)
+	return err
+}
+
+// writeAnnotatedFrames is writeFrames with each frame followed by the
+// notes attached to it. Notes that match no frame are written last.
+func writeAnnotatedFrames(buf *bytes.Buffer, frames []StackFrame, notes []frameNote) {
+	if len(notes) == 0 {
+		writeFrames(buf, frames)
+		return
+	}
+	used := make([]bool, len(notes))
+	for i := range frames {
+		frame := frames[i]
+		if StackReverse {
+			frame = frames[len(frames)-1-i]
+		}
+		buf.WriteString(formatFrame(frame) + "\n")
+		for j, n := range notes {
+			if !used[j] && n.matches(frame) {
+				buf.WriteString("\t> " + n.note + "\n")
+				used[j] = true
+			}
+		}
+	}
+	for j, n := range notes {
+		if !used[j] {
+			fmt.Fprintf(buf, "\t> %s (%s:%d)\n", n.note, RelativeFilePath(n.file), n.line)
+		}
+	}
+}
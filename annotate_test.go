@@ -0,0 +1,72 @@
+package errgo
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteAnnotatedFrames(t *testing.T) {
+	defer func(reverse bool, f func(StackFrame) string) {
+		StackReverse, FrameFormatter = reverse, f
+	}(StackReverse, FrameFormatter)
+	FrameFormatter, _ = FrameTemplate("{{.FunctionName}}")
+
+	frames := []StackFrame{
+		{File: "/app/load.go", Package: "example.com/app", FunctionName: "load"},
+		{File: "/app/main.go", Package: "main", FunctionName: "main"},
+	}
+	onLoad := frameNote{function: "example.com/app.load", file: "/app/load.go", line: 9, note: "retry 3"}
+	onMain := frameNote{function: "main.main", file: "/app/main.go", line: 4, note: "startup"}
+	elsewhere := frameNote{function: "example.com/app.save", file: "/app/save.go", line: 21, note: "dirty"}
+
+	cases := []struct {
+		name     string
+		reverse  bool
+		notes    []frameNote
+		expected string
+	}{
+		{"no notes", false, nil, "load\nmain\n"},
+		{"under frames", false, []frameNote{onMain, onLoad}, "load\n\t> retry 3\nmain\n\t> startup\n"},
+		{"reversed", true, []frameNote{onLoad}, "main\nload\n\t> retry 3\n"},
+		{"no matching frame", false, []frameNote{elsewhere}, "load\nmain\n\t> dirty (/app/save.go:21)\n"},
+		{"same frame twice", false, []frameNote{onLoad, onLoad}, "load\n\t> retry 3\n\t> retry 3\nmain\n"},
+	}
+	for _, c := range cases {
+		StackReverse = c.reverse
+		buf := bytes.Buffer{}
+		writeAnnotatedFrames(&buf, frames, c.notes)
+		if actual := buf.String(); actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, actual)
+		}
+	}
+}
+
+func annotateInHelper(err *StackableError) *StackableError {
+	return err.AnnotateFrame("in helper")
+}
+
+func TestAnnotateFrame(t *testing.T) {
+	requireStacks(t)
+
+	err := annotateInHelper(Wrap(errors.New("boom")))
+	stack := err.Stack()
+	if !strings.Contains(stack, "\n\t> in helper (") {
+		t.Errorf("expected the note of a frame outside the stack after it, got:\n%s", stack)
+	}
+
+	err = Wrap(errors.New("boom")).AnnotateFrame("in test")
+	lines := strings.Split(err.Stack(), "\n")
+	if len(lines) < 2 || !strings.Contains(lines[0], "TestAnnotateFrame") || lines[1] != "\t> in test" {
+		t.Errorf("expected the note below the frame of the test, got:\n%s", err.Stack())
+	}
+}
+
+func TestAnnotateFrameFrozen(t *testing.T) {
+	frozen := Wrap(errors.New("boom")).Freeze()
+	annotated := frozen.AnnotateFrame("note")
+	if annotated == frozen || len(frozen.annotations) != 0 || len(annotated.annotations) != 1 {
+		t.Error("expected the note to be added to a copy of the frozen error")
+	}
+}
@@ -0,0 +1,83 @@
+// Package assert checks invariants, returning a StackableError of kind
+// errgo.Internal whose stack starts at the violated check.
+package assert
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/freemish/errgo"
+)
+
+// violated is called by the exported checks, so the stack skips both it
+// and the check to start at the caller of the check.
+func violated(msg string) error {
+	return errgo.ESkip(2, "", errgo.Internal, nil, "assertion failed: ", msg)
+}
+
+// Ensure returns an error with message msg if cond is false.
+func Ensure(cond bool, msg string) error {
+	if cond {
+		return nil
+	}
+	return violated(msg)
+}
+
+// Ensuref is like Ensure with a formatted message.
+func Ensuref(cond bool, format string, args ...interface{}) error {
+	if cond {
+		return nil
+	}
+	return violated(fmt.Sprintf(format, args...))
+}
+
+// NotNil returns an error if v is nil, including a nil pointer, map,
+// slice, channel or function stored in an interface.
+func NotNil(name string, v interface{}) error {
+	if v == nil {
+		return violated(name + " is nil")
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		if rv.IsNil() {
+			return violated(fmt.Sprintf("%s is nil (%T)", name, v))
+		}
+	}
+	return nil
+}
+
+// InRange returns an error unless min <= v <= max.
+func InRange(name string, v, min, max int) error {
+	if v >= min && v <= max {
+		return nil
+	}
+	return violated(fmt.Sprintf("%s is %d, want between %d and %d", name, v, min, max))
+}
+
+// NotEmpty returns an error if s is the empty string.
+func NotEmpty(name, s string) error {
+	if s != "" {
+		return nil
+	}
+	return violated(name + " is empty")
+}
+
+// Equal returns an error if got and want are not equal according to
+// reflect.DeepEqual.
+func Equal(name string, got, want interface{}) error {
+	if reflect.DeepEqual(got, want) {
+		return nil
+	}
+	return violated(fmt.Sprintf("%s is %v, want %v", name, got, want))
+}
+
+// Must panics with err if it is not nil, for invariants whose violation
+// should stop the program:
+//
+//	assert.Must(assert.NotNil("cfg", cfg))
+func Must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
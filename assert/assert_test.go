@@ -0,0 +1,77 @@
+package assert
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+func TestChecks(t *testing.T) {
+	var nilPtr *int
+	var nilMap map[string]int
+	tests := []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{"Ensure true", Ensure(true, "unused"), ""},
+		{"Ensure false", Ensure(false, "queue drained"), "assertion failed: queue drained"},
+		{"Ensuref", Ensuref(false, "%d workers", 3), "assertion failed: 3 workers"},
+		{"NotNil", NotNil("cfg", 1), ""},
+		{"NotNil nil", NotNil("cfg", nil), "assertion failed: cfg is nil"},
+		{"NotNil nil pointer", NotNil("cfg", nilPtr), "assertion failed: cfg is nil (*int)"},
+		{"NotNil nil map", NotNil("m", nilMap), "assertion failed: m is nil (map[string]int)"},
+		{"InRange", InRange("n", 5, 1, 5), ""},
+		{"InRange out", InRange("n", 6, 1, 5), "assertion failed: n is 6, want between 1 and 5"},
+		{"NotEmpty", NotEmpty("name", "x"), ""},
+		{"NotEmpty empty", NotEmpty("name", ""), "assertion failed: name is empty"},
+		{"Equal", Equal("ids", []int{1}, []int{1}), ""},
+		{"Equal differs", Equal("n", 1, 2), "assertion failed: n is 1, want 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.msg == "" {
+				if tt.err != nil {
+					t.Fatalf("expected no error, got %v", tt.err)
+				}
+				return
+			}
+			if tt.err == nil || tt.err.Error() != tt.msg {
+				t.Fatalf("expected %q, got %v", tt.msg, tt.err)
+			}
+			if kind := errgo.KindOf(tt.err); kind != errgo.Internal {
+				t.Errorf("expected kind Internal, got %v", kind)
+			}
+		})
+	}
+}
+
+func TestStackStartsAtCaller(t *testing.T) {
+	err := Ensure(false, "x")
+
+	var e *errgo.StackableError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected a StackableError, got %T", err)
+	}
+	frames := e.StackFrames()
+	if len(frames) == 0 {
+		t.Skip("built without stacks")
+	}
+	if !strings.HasSuffix(frames[0].FunctionName, "TestStackStartsAtCaller") {
+		t.Errorf("expected the stack to start at the test, got %s.%s", frames[0].Package, frames[0].FunctionName)
+	}
+}
+
+func TestMust(t *testing.T) {
+	Must(nil)
+
+	err := Ensure(false, "x")
+	defer func() {
+		if r := recover(); r != err {
+			t.Errorf("expected a panic with the error, got %v", r)
+		}
+	}()
+	Must(err)
+}
@@ -0,0 +1,94 @@
+package errgo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxBreadcrumbs is the number of breadcrumbs kept per context; older
+// ones are dropped first.
+var MaxBreadcrumbs = 20
+
+// A Breadcrumb records a significant step taken before an error occurred.
+type Breadcrumb struct {
+	Time    time.Time
+	Message string
+	Fields  []Field
+}
+
+func (b Breadcrumb) String() string {
+	var s strings.Builder
+	s.WriteString(b.Time.Format("15:04:05.000") + " " + b.Message)
+	for _, field := range b.Fields {
+		fmt.Fprintf(&s, " %s=%v", field.Key, field.Value)
+	}
+	return s.String()
+}
+
+type breadcrumbKey struct{}
+
+type breadcrumbTrail struct {
+	sync.Mutex
+	crumbs []Breadcrumb
+}
+
+// WithBreadcrumbs returns a context that collects breadcrumbs added with
+// AddBreadcrumb, typically installed once per request.
+func WithBreadcrumbs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, breadcrumbKey{}, &breadcrumbTrail{})
+}
+
+// AddBreadcrumb records a step in the trail of ctx. The kv arguments are
+// alternating keys and values. It does nothing if ctx was not set up with
+// WithBreadcrumbs.
+func AddBreadcrumb(ctx context.Context, msg string, kv ...interface{}) {
+	trail, _ := ctx.Value(breadcrumbKey{}).(*breadcrumbTrail)
+	if trail == nil {
+		return
+	}
+
+	crumb := Breadcrumb{Time: now(), Message: msg}
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		var value interface{}
+		if i+1 < len(kv) {
+			value = kv[i+1]
+		}
+		crumb.Fields = append(crumb.Fields, Field{Key: key, Value: value})
+	}
+
+	trail.Lock()
+	defer trail.Unlock()
+	trail.crumbs = append(trail.crumbs, crumb)
+	if MaxBreadcrumbs > 0 && len(trail.crumbs) > MaxBreadcrumbs {
+		trail.crumbs = append([]Breadcrumb(nil), trail.crumbs[len(trail.crumbs)-MaxBreadcrumbs:]...)
+	}
+}
+
+// breadcrumbs returns a copy of the trail of ctx.
+func breadcrumbs(ctx context.Context) []Breadcrumb {
+	trail, _ := ctx.Value(breadcrumbKey{}).(*breadcrumbTrail)
+	if trail == nil {
+		return nil
+	}
+	trail.Lock()
+	defer trail.Unlock()
+	return append([]Breadcrumb(nil), trail.crumbs...)
+}
+
+// Breadcrumbs returns the trail attached to the error by WrapContext,
+// oldest first.
+func (err *StackableError) Breadcrumbs() []Breadcrumb {
+	return err.breadcrumbs
+}
+
+func (err *StackableError) breadcrumbLines() string {
+	var b strings.Builder
+	for _, crumb := range err.breadcrumbs {
+		b.WriteString(crumb.String() + "\n")
+	}
+	return b.String()
+}
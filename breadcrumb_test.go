@@ -0,0 +1,85 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreadcrumbs(t *testing.T) {
+	defer func(max int) { MaxBreadcrumbs = max }(MaxBreadcrumbs)
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	SetNow(func() time.Time { return at })
+	defer SetNow(nil)
+
+	cases := []struct {
+		name     string
+		max      int
+		ctx      context.Context
+		add      []string
+		expected []string
+	}{
+		{"no trail", 20, context.Background(), []string{"a", "b"}, nil},
+		{"trail", 20, WithBreadcrumbs(context.Background()), []string{"a", "b"}, []string{"a", "b"}},
+		{"oldest dropped", 2, WithBreadcrumbs(context.Background()), []string{"a", "b", "c"}, []string{"b", "c"}},
+		{"unlimited", 0, WithBreadcrumbs(context.Background()), []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+	}
+	for _, c := range cases {
+		MaxBreadcrumbs = c.max
+		for _, msg := range c.add {
+			AddBreadcrumb(c.ctx, msg)
+		}
+
+		var actual []string
+		for _, crumb := range WrapContext(c.ctx, errors.New("boom")).Breadcrumbs() {
+			actual = append(actual, crumb.Message)
+		}
+		if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestBreadcrumbFields(t *testing.T) {
+	SetNow(func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 120e6, time.UTC) })
+	defer SetNow(nil)
+
+	cases := []struct {
+		name     string
+		kv       []interface{}
+		expected string
+	}{
+		{"none", nil, "07:08:09.120 loaded"},
+		{"pairs", []interface{}{"id", 7, "cached", true}, "07:08:09.120 loaded id=7 cached=true"},
+		{"missing value", []interface{}{"id"}, "07:08:09.120 loaded id=<nil>"},
+	}
+	for _, c := range cases {
+		ctx := WithBreadcrumbs(context.Background())
+		AddBreadcrumb(ctx, "loaded", c.kv...)
+		crumbs := WrapContext(ctx, errors.New("boom")).Breadcrumbs()
+		if len(crumbs) != 1 || crumbs[0].String() != c.expected {
+			t.Errorf("%s: expected %q, got %v", c.name, c.expected, crumbs)
+		}
+	}
+}
+
+func TestBreadcrumbsInTrace(t *testing.T) {
+	SetNow(func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) })
+	defer SetNow(nil)
+
+	ctx := WithBreadcrumbs(context.Background())
+	AddBreadcrumb(ctx, "loaded user", "id", 7)
+	err := WrapContext(ctx, errors.New("boom"))
+	AddBreadcrumb(ctx, "too late")
+
+	trace := err.StackTrace()
+	if !strings.Contains(trace, "BREADCRUMBS:\n07:08:09.000 loaded user id=7\n") {
+		t.Errorf("expected the breadcrumbs in:\n%s", trace)
+	}
+	if strings.Contains(trace, "too late") {
+		t.Errorf("expected breadcrumbs added after the error to be left out of:\n%s", trace)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !tinygo && !errgo_nostack
+
+package errgo
+
+import "runtime"
+
+// captureStack returns the program counters of the calling function's
+// stack, skipping skip callers.
+func captureStack(skip int) []uintptr {
+	stack := make([]uintptr, MaxStackDepth)
+	return stack[:callersInto(1+skip, stack)]
+}
+
+// callersInto fills stack with the program counters of the calling
+// function's stack, skipping skip callers, and returns how many it wrote.
+func callersInto(skip int, stack []uintptr) int {
+	return dropSkipped(stack[:runtime.Callers(2+skip, stack)])
+}
@@ -0,0 +1,17 @@
+//go:build errgo_nostack
+
+package errgo
+
+// captureStack captures nothing when built with the errgo_nostack tag, so
+// errors only carry their message, prefixes and other annotations. The
+// StackableError type keeps its stack fields, so code using them still
+// compiles, but they stay empty and cost a nil slice and an unresolved
+// frame cache per error.
+func captureStack(skip int) []uintptr {
+	return nil
+}
+
+// callersInto captures nothing when built with the errgo_nostack tag.
+func callersInto(skip int, stack []uintptr) int {
+	return 0
+}
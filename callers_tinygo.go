@@ -0,0 +1,24 @@
+//go:build tinygo && !errgo_nostack
+
+package errgo
+
+import "runtime"
+
+// captureStack records only the immediate caller under TinyGo, whose
+// runtime cannot walk the stack. If even that is unavailable, errors carry
+// no stack and only their message is reported.
+func captureStack(skip int) []uintptr {
+	stack := make([]uintptr, 1)
+	return stack[:callersInto(1+skip, stack)]
+}
+
+// callersInto stores the immediate caller, skipping skip callers, in
+// stack and returns 1, or returns 0 if it is unknown or skipped.
+func callersInto(skip int, stack []uintptr) int {
+	pc, _, _, ok := runtime.Caller(1 + skip)
+	if !ok || pc == 0 || len(stack) == 0 {
+		return 0
+	}
+	stack[0] = pc
+	return dropSkipped(stack[:1])
+}
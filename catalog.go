@@ -0,0 +1,74 @@
+package errgo
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// grpcCodeNames are the names of the google.rpc.Code values.
+var grpcCodeNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// WriteCatalogMarkdown writes a Markdown reference of every registered
+// error code: its default message, kind, HTTP and gRPC status and the
+// package that declares it. Since codes are registered at init time, it
+// is usually called from a small program, run by go:generate, that
+// imports the packages declaring the codes:
+//
+//	func main() {
+//		errgo.WriteCatalogMarkdown(os.Stdout)
+//	}
+func WriteCatalogMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Error codes\n\n")
+	b.WriteString("| Code | Message | Kind | HTTP | gRPC | Package |\n")
+	b.WriteString("|------|---------|------|------|------|---------|\n")
+
+	for _, def := range Definitions() {
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s | `%s` |\n",
+			def.Code,
+			markdownEscape(def.Message),
+			kindText(def.Kind),
+			httpStatusText(def.HTTPStatus),
+			grpcCodeText(def.GRPCCode),
+			def.Package)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// kindText names the kind by its constant, as catalogs given to errgogen
+// and the OpenAPI components written by errgohttp do.
+func kindText(kind Kind) string {
+	if name := kind.Name(); name != "" {
+		return name
+	}
+	return kind.String()
+}
+
+func httpStatusText(status int) string {
+	if status == 0 {
+		return "-"
+	}
+	return fmt.Sprint(status)
+}
+
+func grpcCodeText(code int) string {
+	switch {
+	case code == 0:
+		return "-"
+	case code > 0 && code < len(grpcCodeNames):
+		return fmt.Sprintf("%d %s", code, grpcCodeNames[code])
+	}
+	return fmt.Sprint(code)
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
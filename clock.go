@@ -0,0 +1,31 @@
+package errgo
+
+import "time"
+
+var nowFunc = time.Now
+
+// SetNow replaces the clock used for error timestamps, breadcrumbs,
+// suppression windows, retries and the journal, so tests that depend on
+// them can be deterministic. Passing nil restores time.Now. It is not safe
+// to call while errors are being created.
+func SetNow(fn func() time.Time) {
+	if fn == nil {
+		fn = time.Now
+	}
+	nowFunc = fn
+}
+
+func now() time.Time {
+	return nowFunc()
+}
+
+// Now returns the current time from the clock set by SetNow, for
+// subpackages that record durations on errors.
+func Now() time.Time {
+	return nowFunc()
+}
+
+// Time returns when the error was created.
+func (err *StackableError) Time() time.Time {
+	return err.timestamp
+}
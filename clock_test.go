@@ -0,0 +1,43 @@
+package errgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetNow(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	later := created.Add(time.Hour)
+	SetNow(func() time.Time { return created })
+	defer SetNow(nil)
+
+	frozen := Wrap(errors.New("boom")).Freeze()
+	SetNow(func() time.Time { return later })
+
+	cases := []struct {
+		name     string
+		err      *StackableError
+		expected time.Time
+	}{
+		{"frozen", frozen, created},
+		{"copy of frozen", frozen.WithField("id", 7), created},
+		{"wrapped again", Wrap(frozen), created},
+		{"new", Wrap(errors.New("boom")), later},
+		{"op", E("db.Get", NotExist, nil), later},
+		{"literal", &StackableError{Err: errors.New("boom")}, time.Time{}},
+	}
+	for _, c := range cases {
+		if actual := c.err.Time(); !actual.Equal(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+	if actual := Now(); !actual.Equal(later) {
+		t.Errorf("expected Now to use the clock, got %v", actual)
+	}
+
+	SetNow(nil)
+	if actual := Now(); time.Since(actual) > time.Minute || actual.Equal(later) {
+		t.Errorf("expected nil to restore time.Now, got %v", actual)
+	}
+}
@@ -0,0 +1,195 @@
+// Command errgogen generates Go code for an error catalog: a typed
+// constant for each code, a constructor returning a *errgo.StackableError, and an init
+// function registering the codes with errgo.Register.
+//
+// It is meant to be run from a go:generate directive:
+//
+//	//go:generate errgogen -in errors.json -out errors_gen.go
+//
+// The catalog is a JSON file like:
+//
+//	{
+//	  "package": "apperrors",
+//	  "errors": [
+//	    {"name": "UserNotFound", "code": "user_not_found", "message": "user not found",
+//	     "kind": "NotExist", "http": 404, "grpc": 5}
+//	  ]
+//	}
+//
+// The kind is the name of an errgo Kind constant and may be omitted.
+// Unknown kinds, like other mistakes in the catalog, are reported with
+// the line of the offending entry.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"text/template"
+
+	"github.com/freemish/errgo"
+)
+
+type catalog struct {
+	Package string  `json:"package"`
+	Errors  []entry `json:"errors"`
+}
+
+type entry struct {
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Kind    string `json:"kind"`
+	HTTP    int    `json:"http"`
+	GRPC    int    `json:"grpc"`
+
+	line int // line of the entry in the catalog
+}
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by errgogen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import "github.com/freemish/errgo"
+
+// Code is an error code registered by this package.
+type Code string
+
+// Error codes.
+const (
+{{- range .Errors}}
+	Code{{.Name}} Code = {{printf "%q" .Code}}
+{{- end}}
+)
+
+func init() {
+{{- range .Errors}}
+	errgo.Register(errgo.Definition{
+		Code:       string(Code{{.Name}}),
+		Message:    {{printf "%q" .Message}},
+		Kind:       errgo.{{.Kind}},
+		HTTPStatus: {{.HTTP}},
+		GRPCCode:   {{.GRPC}},
+	})
+{{- end}}
+}
+{{range .Errors}}
+// New{{.Name}} returns an error with code {{.Code}}. If err is nil, the
+// message is {{printf "%q" .Message}}.
+func New{{.Name}}(err error) *errgo.StackableError {
+	return errgo.NewCode(string(Code{{.Name}}), err)
+}
+{{end}}`))
+
+func main() {
+	in := flag.String("in", "errors.json", "error catalog to read")
+	out := flag.String("out", "errors_gen.go", "Go file to write")
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name, if not set in the catalog")
+	flag.Parse()
+
+	if err := generate(*in, *out, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "errgogen:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(in, out, pkg string) error {
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+
+	cat, err := parseCatalog(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", in, err)
+	}
+	if cat.Package == "" {
+		cat.Package = pkg
+	}
+	if cat.Package == "" {
+		return fmt.Errorf("%s: no package name given", in)
+	}
+
+	seen := map[string]bool{}
+	for i, e := range cat.Errors {
+		if !token.IsIdentifier(e.Name) || !token.IsExported(e.Name) {
+			return fmt.Errorf("%s:%d: invalid name %q", in, e.line, e.Name)
+		}
+		if e.Code == "" || seen[e.Code] {
+			return fmt.Errorf("%s:%d: missing or duplicate code for %s", in, e.line, e.Name)
+		}
+		seen[e.Code] = true
+		if e.Kind == "" {
+			cat.Errors[i].Kind = "Other"
+		} else if _, ok := errgo.ParseKind(e.Kind); !ok {
+			return fmt.Errorf("%s:%d: unknown kind %q for %s", in, e.line, e.Kind, e.Name)
+		}
+	}
+
+	buf := bytes.Buffer{}
+	err = tmpl.Execute(&buf, struct {
+		catalog
+		Source string
+	}{cat, in})
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0644)
+}
+
+// parseCatalog decodes a catalog, recording the line each entry starts
+// on so errors can point at it.
+func parseCatalog(data []byte) (catalog, error) {
+	var cat catalog
+	if err := json.Unmarshal(data, &cat); err != nil {
+		return cat, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return cat, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return cat, err
+		}
+		if key != "errors" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return cat, err
+			}
+			continue
+		}
+		if _, err := dec.Token(); err != nil {
+			return cat, err
+		}
+		for i := 0; dec.More() && i < len(cat.Errors); i++ {
+			cat.Errors[i].line = lineAt(data, dec.InputOffset())
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return cat, err
+			}
+		}
+		break
+	}
+	return cat, nil
+}
+
+// lineAt returns the line of the first value at or after offset, skipping
+// the whitespace and separators the decoder has not consumed yet.
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n,"), data[offset]) >= 0 {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
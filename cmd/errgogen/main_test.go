@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo/errtest"
+)
+
+func TestGenerate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "errors_gen.go")
+	if err := generate("testdata/errors.json", out, ""); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := "testdata/errors_gen.go.golden"
+	if update, _ := strconv.ParseBool(os.Getenv(errtest.UpdateEnv)); update {
+		if err := os.WriteFile(golden, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run with %s=1 to create it)", err, errtest.UpdateEnv)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("generated code does not match %s:\n%s", golden, actual)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := map[string]struct {
+		catalog  string
+		expected string
+	}{
+		"unknown kind": {
+			catalog: `{
+  "package": "p",
+  "errors": [
+    {"name": "A", "code": "a", "kind": "NotExist"},
+
+    {"name": "B", "code": "b", "kind": "not_found"}
+  ]
+}`,
+			expected: `:6: unknown kind "not_found" for B`,
+		},
+		"duplicate code": {
+			catalog:  `{"package": "p", "errors": [{"name": "A", "code": "a"}, {"name": "B", "code": "a"}]}`,
+			expected: `:1: missing or duplicate code for B`,
+		},
+		"invalid name": {
+			catalog:  "{\n\"errors\": [\n{\"name\": \"lower\", \"code\": \"a\"}]}",
+			expected: `:3: invalid name "lower"`,
+		},
+	}
+
+	for name, c := range cases {
+		dir := t.TempDir()
+		in := filepath.Join(dir, "errors.json")
+		if err := os.WriteFile(in, []byte(c.catalog), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := generate(in, filepath.Join(dir, "errors_gen.go"), "p")
+		if err == nil || !strings.HasSuffix(err.Error(), c.expected) {
+			t.Errorf("%s: expected an error ending in %q, got %v", name, c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package errgo
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// maxReferenceStacks is the number of full stacks a StackCompressor keeps
+// to compare new stacks against.
+const maxReferenceStacks = 16
+
+// StackCompressor formats stack traces for high-volume logging: the first
+// stack is printed in full and labelled, and later stacks that share their
+// outer frames with an earlier one print only the frames that differ,
+// followed by a reference to the earlier stack. Use one compressor per
+// log stream, e.g. per request or worker. It is safe for concurrent use.
+type StackCompressor struct {
+	mu     sync.Mutex
+	nextID int
+	refs   []referenceStack
+}
+
+type referenceStack struct {
+	id     int
+	frames []StackFrame
+}
+
+// NewStackCompressor returns an empty StackCompressor.
+func NewStackCompressor() *StackCompressor {
+	return &StackCompressor{nextID: 1}
+}
+
+// StackTrace formats err like StackableError.StackTrace, with the code,
+// time, fields, origin, breadcrumbs, notes and causes that the
+// StackTrace* variables ask for, but compressing its own stack against
+// the stacks formatted before it. Notes added with AnnotateFrame are not
+// printed.
+func (c *StackCompressor) StackTrace(err *StackableError) string {
+	frames := err.StackFrames()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var best *referenceStack
+	shared := 0
+	for i := range c.refs {
+		if n := commonOuterFrames(frames, c.refs[i].frames); n > shared {
+			best, shared = &c.refs[i], n
+		}
+	}
+
+	buf := bytes.Buffer{}
+	err.writeTraceHeader(&buf)
+
+	if best == nil {
+		id := c.nextID
+		c.nextID++
+		c.refs = append(c.refs, referenceStack{id: id, frames: frames})
+		if len(c.refs) > maxReferenceStacks {
+			c.refs = c.refs[1:]
+		}
+		fmt.Fprintf(&buf, "STACK #%d:\n", id)
+		writeFrames(&buf, frames)
+	} else {
+		writeFrames(&buf, frames[:len(frames)-shared])
+		fmt.Fprintf(&buf, "... %d frames shared with STACK #%d\n", shared, best.id)
+	}
+
+	err.writeTraceFooter(&buf)
+	return buf.String()
+}
+
+// commonOuterFrames returns how many outermost frames a and b share.
+func commonOuterFrames(a, b []StackFrame) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package errgo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// compressTestErrors returns errors whose stacks differ only in their
+// innermost frame.
+func compressTestErrors() (*StackableError, *StackableError) {
+	var errs []*StackableError
+	for _, msg := range []string{"first", "second"} {
+		if msg == "first" {
+			errs = append(errs, Wrap(errors.New(msg)))
+		} else {
+			errs = append(errs, compressTestError(msg))
+		}
+	}
+	return errs[0], errs[1]
+}
+
+func compressTestError(msg string) *StackableError {
+	return Wrap(errors.New(msg))
+}
+
+func TestStackCompressor(t *testing.T) {
+	requireStacks(t)
+	defer func(show bool) { StackTraceShowFields = show }(StackTraceShowFields)
+	StackTraceShowFields = true
+
+	first, second := compressTestErrors()
+	first = first.WithField("user", 7)
+	AddNote(first, "retried once")
+	c := NewStackCompressor()
+
+	trace := c.StackTrace(first)
+	for _, expected := range []string{"ERROR: first\n", "FIELDS:\nuser=7\n", "STACK #1:\n", "compressTestErrors", "NOTES:\nretried once\n"} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("expected %q in the first trace:\n%s", expected, trace)
+		}
+	}
+	if full := first.StackTrace(); strings.Replace(trace, "STACK #1:\n", "", 1) != full {
+		t.Errorf("expected the first trace to match StackTrace:\n%s\ngot:\n%s", full, trace)
+	}
+
+	trace = c.StackTrace(second)
+	if !strings.HasPrefix(trace, "ERROR: second\n") || !strings.Contains(trace, "frames shared with STACK #1\n") {
+		t.Errorf("expected the second stack to be compressed:\n%s", trace)
+	}
+	if strings.Contains(trace, "STACK #2") {
+		t.Errorf("expected no new reference stack:\n%s", trace)
+	}
+}
@@ -0,0 +1,34 @@
+package errgo
+
+import "context"
+
+// WrapContext makes a StackableError from the given value like Wrap, and
+// attaches information carried by ctx: the goroutine origins recorded by
+// GoWithOrigin, the breadcrumbs added with AddBreadcrumb and, as
+// prefixes, the names of the scopes opened with PushScope.
+func WrapContext(ctx context.Context, e interface{}) *StackableError {
+	err := wrap(e, 1)
+	origin, _ := ctx.Value(goOriginKey{}).(*goOrigin)
+	crumbs := breadcrumbs(ctx)
+	scopes := newScopes(ctx, err)
+	if (origin == nil || err.origin != nil) && len(crumbs) == 0 && len(scopes) == 0 {
+		return err
+	}
+
+	if err.frozen {
+		err = err.clone()
+	}
+	if err.origin == nil {
+		err.origin = origin
+	}
+	if len(crumbs) > 0 {
+		err.breadcrumbs = crumbs
+	}
+	if len(scopes) > 0 {
+		for _, s := range scopes {
+			err.Prefixes = append(err.Prefixes, s.name)
+		}
+		err.scope = scopes[0]
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package errgo
+
+import "sync"
+
+var converters struct {
+	sync.RWMutex
+	fns []func(error) (*StackableError, bool)
+}
+
+// RegisterConverter adds a function that Wrap consults before wrapping an
+// error that is not already a *StackableError. The first converter that
+// returns true supplies the StackableError, which lets applications fill
+// in codes, kinds or details for third-party error types once, globally.
+//
+// Wrap attaches the stack of its own caller to the returned error, so a
+// converter can simply return a literal such as
+// &errgo.StackableError{Err: err, Code: "..."}. Converters must not call
+// Wrap on the error they are given.
+func RegisterConverter(fn func(error) (*StackableError, bool)) {
+	converters.Lock()
+	defer converters.Unlock()
+	converters.fns = append(converters.fns, fn)
+}
+
+// convert returns the result of the first converter accepting err, or nil.
+func convert(err error) *StackableError {
+	converters.RLock()
+	defer converters.RUnlock()
+	for _, fn := range converters.fns {
+		if e, ok := fn(err); ok && e != nil {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package errgo
+
+import (
+	"errors"
+	"testing"
+)
+
+// rateLimitError is accepted by both converters registered below; the
+// first one registered wins.
+type rateLimitError struct{ retryAfter int }
+
+func (rateLimitError) Error() string { return "rate limited" }
+
+// lockedError is converted to a frozen error, which Wrap must not modify.
+type lockedError struct{}
+
+func (lockedError) Error() string { return "locked" }
+
+var frozenLocked = (&StackableError{Err: lockedError{}, Code: "converter_test.locked"}).Freeze()
+
+func init() {
+	RegisterConverter(func(err error) (*StackableError, bool) {
+		if r, ok := err.(rateLimitError); ok && r.retryAfter > 0 {
+			return &StackableError{Err: err, Code: "converter_test.rate_limit", Kind: Transient}, true
+		}
+		return nil, false
+	})
+	RegisterConverter(func(err error) (*StackableError, bool) {
+		if _, ok := err.(rateLimitError); ok {
+			return &StackableError{Err: err, Code: "converter_test.rate_limit_unknown"}, true
+		}
+		return nil, false
+	})
+	RegisterConverter(func(err error) (*StackableError, bool) {
+		if _, ok := err.(lockedError); ok {
+			return frozenLocked, true
+		}
+		return nil, false
+	})
+}
+
+func TestConverters(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code string
+		kind Kind
+	}{
+		{"first converter", rateLimitError{retryAfter: 5}, "converter_test.rate_limit", Transient},
+		{"first converter declines", rateLimitError{}, "converter_test.rate_limit_unknown", Other},
+		{"no converter", errors.New("boom"), "", Other},
+		{"frozen", lockedError{}, "converter_test.locked", Other},
+	}
+	for _, c := range cases {
+		err := Wrap(c.err)
+		if err.Code != c.code {
+			t.Errorf("%s: expected code %q, got %q", c.name, c.code, err.Code)
+		}
+		if err.Kind != c.kind {
+			t.Errorf("%s: expected kind %v, got %v", c.name, c.kind, err.Kind)
+		}
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expected the error to wrap %v", c.name, c.err)
+		}
+		if frames := err.StackFrames(); len(frames) > 0 && frames[0].FunctionName != "TestConverters" {
+			t.Errorf("%s: expected the stack to start at the caller of Wrap, got %s", c.name, frames[0].FunctionName)
+		}
+	}
+}
+
+func TestConverterFrozen(t *testing.T) {
+	err := Wrap(lockedError{})
+	if err == frozenLocked {
+		t.Fatal("expected a copy of the frozen error")
+	}
+	if len(frozenLocked.Callers()) != 0 {
+		t.Error("expected the frozen error to be left without a stack")
+	}
+}
@@ -0,0 +1,71 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+)
+
+// CaptureCrashes makes hard crashes of this process show up in the same
+// pipeline as handled errors. It reports, through r, the crash left in
+// the file at path by a previous run, if any, and arranges with
+// debug.SetCrashOutput for the next fatal panic or runtime error to be
+// written to that file. Crash output is captured even if the previous
+// crash could not be reported; that crash is then kept in the file and
+// the error is returned. It should be called early in main. It returns
+// whether a crash was reported.
+func CaptureCrashes(ctx context.Context, path string, r Reporter) (bool, error) {
+	reported, harvestErr := HarvestCrash(ctx, path, r)
+
+	// Append rather than truncate, so a crash that could not be harvested
+	// is not lost; a harvested file has already been emptied.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err == nil {
+		defer f.Close()
+		err = setCrashOutput(f)
+	}
+	return reported, errors.Join(harvestErr, err)
+}
+
+// HarvestCrash reads the crash output written to the file at path, parses
+// it with ParsePanic and reports it through r as a StackableError of kind
+// Internal and severity Critical, timestamped with the file's modification
+// time. The file is then truncated so the crash is reported only once. It
+// returns whether a crash was reported; a missing or empty file is not an
+// error.
+func HarvestCrash(ctx context.Context, path string, r Reporter) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	e := parseCrash(string(data))
+	e.Kind = Internal
+	e.Severity = SeverityCritical
+	e.timestamp = info.ModTime()
+	r.Report(ctx, e.WithField("crash_file", path))
+
+	return true, os.Truncate(path, 0)
+}
+
+// parseCrash parses crash output, which starts with "panic: " for panics
+// and "fatal error: " for fatal runtime errors. Output that cannot be
+// parsed is kept as the message of an error without a stack.
+func parseCrash(text string) *StackableError {
+	text = strings.TrimLeft(text, "\n")
+	if strings.HasPrefix(text, "fatal error: ") {
+		text = "panic: " + strings.TrimPrefix(text, "fatal error: ")
+	}
+	if e, err := ParsePanic(text); err == nil {
+		return e
+	}
+	return WrapFrames(errors.New(strings.TrimSpace(text)), []StackFrame{})
+}
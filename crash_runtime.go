@@ -0,0 +1,12 @@
+//go:build !tinygo
+
+package errgo
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+func setCrashOutput(f *os.File) error {
+	return debug.SetCrashOutput(f, debug.CrashOptions{})
+}
@@ -0,0 +1,12 @@
+//go:build tinygo
+
+package errgo
+
+import (
+	"errors"
+	"os"
+)
+
+func setCrashOutput(f *os.File) error {
+	return errors.New("errgo: crash output is not supported by TinyGo")
+}
@@ -0,0 +1,17 @@
+package errgo
+
+// AddDetail attaches a typed payload to the error, such as a protocol
+// buffer describing the failure to a client, and returns the error. If the
+// error is frozen, a copy with the detail added is returned instead.
+func (err *StackableError) AddDetail(detail interface{}) *StackableError {
+	if err.frozen {
+		err = err.clone()
+	}
+	err.details = append(err.details, detail)
+	return err
+}
+
+// Details returns the payloads attached with AddDetail, in order.
+func (err *StackableError) Details() []interface{} {
+	return err.details
+}
@@ -0,0 +1,30 @@
+package errgo
+
+import "sync"
+
+var diagnoseKinds struct {
+	sync.RWMutex
+	kinds map[Kind]bool
+}
+
+// DiagnoseKinds makes E and NewCode call WithDiagnostics on every error of
+// the given kinds.
+func DiagnoseKinds(kinds ...Kind) {
+	diagnoseKinds.Lock()
+	defer diagnoseKinds.Unlock()
+	if diagnoseKinds.kinds == nil {
+		diagnoseKinds.kinds = map[Kind]bool{}
+	}
+	for _, kind := range kinds {
+		diagnoseKinds.kinds[kind] = true
+	}
+}
+
+func diagnoseKind(e *StackableError) {
+	diagnoseKinds.RLock()
+	diagnose := diagnoseKinds.kinds[e.Kind]
+	diagnoseKinds.RUnlock()
+	if diagnose {
+		e.WithDiagnostics()
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !tinygo
+
+package errgo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+)
+
+// WithDiagnostics records a snapshot of the runtime as fields of the
+// error: the number of goroutines (goroutines), the bytes of heap in use
+// (heap_inuse_bytes), the number of GC cycles (gc_count) and the duration
+// of the last GC pause (gc_last_pause). It avoids stopping the world, so
+// it is cheap enough to call when an error is created.
+func (err *StackableError) WithDiagnostics() *StackableError {
+	samples := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(samples)
+
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	err = err.WithField("goroutines", runtime.NumGoroutine())
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		err = err.WithField("heap_inuse_bytes", samples[0].Value.Uint64())
+	}
+	err = err.WithField("gc_count", gc.NumGC)
+	if len(gc.Pause) > 0 {
+		err = err.WithField("gc_last_pause", gc.Pause[0])
+	}
+	return err
+}
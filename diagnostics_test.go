@@ -0,0 +1,51 @@
+//go:build !tinygo
+
+package errgo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithDiagnostics(t *testing.T) {
+	err := Wrap(errors.New("boom")).WithField("id", 7).WithDiagnostics()
+
+	for _, key := range []string{"id", "goroutines", "heap_inuse_bytes", "gc_count"} {
+		if _, ok := err.Field(key); !ok {
+			t.Errorf("expected the %s field to be set, got %v", key, err.Fields())
+		}
+	}
+	if n, _ := err.Field("goroutines"); n.(int) < 1 {
+		t.Errorf("expected at least one goroutine, got %v", n)
+	}
+}
+
+func TestDiagnoseKinds(t *testing.T) {
+	diagnoseKinds.Lock()
+	saved := diagnoseKinds.kinds
+	diagnoseKinds.kinds = nil
+	diagnoseKinds.Unlock()
+	defer func() {
+		diagnoseKinds.Lock()
+		diagnoseKinds.kinds = saved
+		diagnoseKinds.Unlock()
+	}()
+	DiagnoseKinds(Permission, Internal)
+
+	cases := []struct {
+		name     string
+		err      *StackableError
+		diagnose bool
+	}{
+		{"listed kind", E("fs.Open", Permission, nil), true},
+		{"other listed kind", E("db.Get", Internal, nil), true},
+		{"kind from the cause", E("svc.Load", Other, E("fs.Open", Permission, nil)), true},
+		{"unlisted kind", E("db.Get", NotExist, nil), false},
+		{"no kind", Wrap(errors.New("boom")), false},
+	}
+	for _, c := range cases {
+		if _, ok := c.err.Field("goroutines"); ok != c.diagnose {
+			t.Errorf("%s: expected diagnostics %v, got fields %v", c.name, c.diagnose, c.err.Fields())
+		}
+	}
+}
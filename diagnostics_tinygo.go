@@ -0,0 +1,12 @@
+//go:build tinygo
+
+package errgo
+
+import "runtime"
+
+// WithDiagnostics records the number of goroutines as the goroutines
+// field of the error. TinyGo does not provide the heap and GC statistics
+// recorded by other builds.
+func (err *StackableError) WithDiagnostics() *StackableError {
+	return err.WithField("goroutines", runtime.NumGoroutine())
+}
@@ -0,0 +1,92 @@
+package errgo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Dump writes a full diagnostic report of the error to w, for attaching
+// to support tickets and crash files: the message, then every layer of
+// the error chain with its creation time, code, kind, severity, fields,
+// details, breadcrumbs, stack and goroutine origins, and the errors
+// joined into any layer. Stacks shared by several layers are written
+// once.
+func (err *StackableError) Dump(w io.Writer) error {
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "ERROR REPORT\nMessage: %s\nFingerprint: %s\n", err.Error(), err.Fingerprint())
+
+	layers, complete := walkChain(err)
+	var lastFrames *frameCache
+	for i, layer := range layers {
+		fmt.Fprintf(&buf, "\n[%d] %T: %s\n", i, layer, layer.Error())
+
+		if e, ok := layer.(*StackableError); ok {
+			e.dumpLayer(&buf, e.frames != nil && e.frames == lastFrames)
+			lastFrames = e.frames
+		}
+
+		if joined, ok := layer.(interface{ Unwrap() []error }); ok {
+			buf.WriteString("Joined errors:\n")
+			for j, err := range joined.Unwrap() {
+				fmt.Fprintf(&buf, "  (%d) %s\n", j, err.Error())
+			}
+		}
+	}
+	if !complete {
+		buf.WriteString("\n(chain cut short: cycle or more than MaxChainDepth layers)\n")
+	}
+
+	_, writeErr := w.Write(buf.Bytes())
+	return writeErr
+}
+
+func (err *StackableError) dumpLayer(buf *bytes.Buffer, sameStack bool) {
+	if !err.timestamp.IsZero() {
+		fmt.Fprintf(buf, "Time: %s\n", err.timestamp.Format(time.RFC3339Nano))
+	}
+	if err.Code != "" {
+		fmt.Fprintf(buf, "Code: %s\n", err.Code)
+	}
+	if err.Op != "" {
+		fmt.Fprintf(buf, "Op: %s\n", err.Op)
+	}
+	if err.Kind != Other {
+		fmt.Fprintf(buf, "Kind: %s\n", err.Kind)
+	}
+	if err.Severity != SeverityUnset {
+		fmt.Fprintf(buf, "Severity: %s\n", err.Severity)
+	}
+	if len(err.fields) > 0 {
+		buf.WriteString("Fields:\n")
+		for _, field := range err.fields {
+			fmt.Fprintf(buf, "  %s=%v\n", field.Key, field.Value)
+		}
+	}
+	if len(err.details) > 0 {
+		buf.WriteString("Details:\n")
+		for _, detail := range err.details {
+			fmt.Fprintf(buf, "  %T: %v\n", detail, detail)
+		}
+	}
+	if len(err.breadcrumbs) > 0 {
+		buf.WriteString("Breadcrumbs:\n")
+		for _, crumb := range err.breadcrumbs {
+			buf.WriteString("  " + crumb.String() + "\n")
+		}
+	}
+	if len(err.notes) > 0 {
+		buf.WriteString("Notes:\n")
+		for _, note := range err.notes {
+			buf.WriteString("  " + note + "\n")
+		}
+	}
+	if sameStack {
+		buf.WriteString("Stack: same as above\n")
+	} else {
+		buf.WriteString("Stack:\n")
+		writeAnnotatedFrames(buf, err.StackFrames(), err.annotations)
+	}
+	buf.WriteString(err.originStack())
+}
@@ -0,0 +1,78 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	SetNow(func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) })
+	defer SetNow(nil)
+
+	detailed := E("db.Get", NotExist, nil).WithField("id", 7).AddDetail(sliceError{"a", "b"})
+	detailed.Code = "user_missing"
+	detailed.Severity = SeverityWarning
+	AddNote(detailed, "cache was cold")
+
+	cases := []struct {
+		name       string
+		err        *StackableError
+		contains   []string
+		notContain []string
+	}{
+		{
+			"layer",
+			detailed,
+			[]string{
+				"ERROR REPORT\nMessage: db.Get: item does not exist\nFingerprint: ",
+				"[0] *errgo.StackableError: db.Get: item does not exist\n",
+				"Time: 2024-05-06T07:08:09Z\nCode: user_missing\nOp: db.Get\nKind: item does not exist\nSeverity: warning\n",
+				"Fields:\n  id=7\n",
+				"Details:\n  errgo.sliceError: a, b\n",
+				"Notes:\n  cache was cold\n",
+				"Stack:\n",
+				"[1] *errors.errorString: item does not exist\n",
+			},
+			[]string{"same as above", "chain cut short"},
+		},
+		{
+			"shared stack",
+			E("svc.Load", Other, E("db.Get", NotExist, nil)),
+			[]string{"[0] *errgo.StackableError: svc.Load: db.Get: item does not exist\nTime: ", "Stack: same as above\n"},
+			nil,
+		},
+		{
+			"joined",
+			Wrap(errors.Join(errors.New("a"), errors.New("b"))),
+			[]string{"Joined errors:\n  (0) a\n  (1) b\n"},
+			nil,
+		},
+		{
+			"wrapped by fmt",
+			Wrap(fmt.Errorf("load: %w", errors.New("boom"))),
+			[]string{"[1] *fmt.wrapError: load: boom\n", "[2] *errors.errorString: boom\n"},
+			[]string{"Code:", "Kind:", "Fields:"},
+		},
+		{"cycle", Wrap(&loopError{}), []string{"(chain cut short: cycle or more than MaxChainDepth layers)\n"}, nil},
+	}
+	for _, c := range cases {
+		buf := strings.Builder{}
+		if err := c.err.Dump(&buf); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		dump := buf.String()
+		for _, s := range c.contains {
+			if !strings.Contains(dump, s) {
+				t.Errorf("%s: expected %q in:\n%s", c.name, s, dump)
+			}
+		}
+		for _, s := range c.notContain {
+			if strings.Contains(dump, s) {
+				t.Errorf("%s: expected no %q in:\n%s", c.name, s, dump)
+			}
+		}
+	}
+}
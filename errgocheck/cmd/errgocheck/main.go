@@ -0,0 +1,14 @@
+// Command errgocheck runs the errgocheck analyzer. It is meant to be used
+// through go vet:
+//
+//	go vet -vettool=$(which errgocheck) ./...
+package main
+
+import (
+	"github.com/freemish/errgo/errgocheck"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(errgocheck.Analyzer)
+}
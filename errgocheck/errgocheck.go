@@ -0,0 +1,268 @@
+// Package errgocheck provides an analyzer that reports exported functions
+// returning errors that were neither created nor wrapped by errgo, so
+// every error leaving a package carries a stack.
+package errgocheck
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const errgoPath = "github.com/freemish/errgo"
+
+// Analyzer checks the error results of exported functions and methods.
+// An error result is accepted if it is nil, has type
+// *errgo.StackableError, or is the result of calling a function from
+// errgo or from the package being analyzed. A returned local variable is
+// accepted if every value assigned to it is. Bare returns and returns of
+// multi-value calls are checked the same way.
+var Analyzer = &analysis.Analyzer{
+	Name:     "errgocheck",
+	Doc:      "report exported functions returning errors not wrapped by errgo",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if pass.Pkg.Path() == errgoPath {
+		return nil, nil
+	}
+
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		fn := n.(*ast.FuncDecl)
+		if !fn.Name.IsExported() || fn.Body == nil {
+			return
+		}
+
+		sig, ok := pass.TypesInfo.Defs[fn.Name].Type().(*types.Signature)
+		if !ok {
+			return
+		}
+		positions := errorResults(sig)
+		if len(positions) == 0 {
+			return
+		}
+
+		c := newChecker(pass, fn, sig)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				c.checkReturn(n, positions)
+			}
+			return true
+		})
+	})
+
+	return nil, nil
+}
+
+// checker checks the returns of one function. It records every value
+// assigned to the function's local variables, so that returning a
+// variable is judged by what was stored in it.
+type checker struct {
+	pass    *analysis.Pass
+	fn      *ast.FuncDecl
+	sig     *types.Signature
+	sources map[*types.Var][]ast.Expr // values assigned to each variable
+	zero    map[*types.Var]bool       // variables that start out nil
+	opaque  map[*types.Var]bool       // variables set in ways not tracked
+}
+
+func newChecker(pass *analysis.Pass, fn *ast.FuncDecl, sig *types.Signature) *checker {
+	c := &checker{
+		pass:    pass,
+		fn:      fn,
+		sig:     sig,
+		sources: map[*types.Var][]ast.Expr{},
+		zero:    map[*types.Var]bool{},
+		opaque:  map[*types.Var]bool{},
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		c.zero[sig.Results().At(i)] = true
+	}
+
+	// Assignments in function literals count too, since they may run
+	// before the enclosing function returns.
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			c.assign(n.Lhs, n.Rhs)
+		case *ast.ValueSpec:
+			names := make([]ast.Expr, len(n.Names))
+			for i, name := range n.Names {
+				names[i] = name
+				if len(n.Values) == 0 {
+					if v := c.localVar(name); v != nil {
+						c.zero[v] = true
+					}
+				}
+			}
+			c.assign(names, n.Values)
+		case *ast.RangeStmt:
+			c.markOpaque(n.Key)
+			c.markOpaque(n.Value)
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				c.markOpaque(n.X)
+			}
+		}
+		return true
+	})
+	return c
+}
+
+func (c *checker) assign(lhs, rhs []ast.Expr) {
+	for i, l := range lhs {
+		v := c.localVar(l)
+		if v == nil {
+			continue
+		}
+		switch {
+		case len(lhs) == len(rhs):
+			c.sources[v] = append(c.sources[v], rhs[i])
+		case len(rhs) == 1:
+			// v, err := f() takes err from the results of the call.
+			c.sources[v] = append(c.sources[v], rhs[0])
+		}
+	}
+}
+
+func (c *checker) markOpaque(expr ast.Expr) {
+	if v := c.localVar(expr); v != nil {
+		c.opaque[v] = true
+	}
+}
+
+// localVar returns the variable expr refers to, if it is declared in the
+// function's body or is one of its named results.
+func (c *checker) localVar(expr ast.Expr) *types.Var {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, ok := c.pass.TypesInfo.ObjectOf(ident).(*types.Var)
+	if !ok {
+		return nil
+	}
+	if c.zero[v] || (v.Pos() >= c.fn.Body.Pos() && v.Pos() < c.fn.Body.End()) {
+		return v
+	}
+	return nil
+}
+
+func (c *checker) checkReturn(ret *ast.ReturnStmt, positions []int) {
+	results := c.sig.Results()
+	switch {
+	case len(ret.Results) == results.Len():
+		for _, i := range positions {
+			if !c.wrapped(ret.Results[i], map[*types.Var]bool{}) {
+				c.report(ret.Results[i].Pos())
+			}
+		}
+	case len(ret.Results) == 0:
+		// A bare return with named results returns their values.
+		for _, i := range positions {
+			if !c.wrappedVar(results.At(i), map[*types.Var]bool{}) {
+				c.report(ret.Pos())
+			}
+		}
+	case len(ret.Results) == 1:
+		// return f() with f returning several values.
+		if call, ok := ast.Unparen(ret.Results[0]).(*ast.CallExpr); ok && !c.wrappedCall(call) {
+			c.report(ret.Results[0].Pos())
+		}
+	}
+}
+
+func (c *checker) report(pos token.Pos) {
+	c.pass.Reportf(pos, "error returned from exported %s is not wrapped by errgo", c.fn.Name.Name)
+}
+
+func (c *checker) wrapped(expr ast.Expr, seen map[*types.Var]bool) bool {
+	expr = ast.Unparen(expr)
+
+	tv := c.pass.TypesInfo.Types[expr]
+	if tv.IsNil() || isStackableError(tv.Type) {
+		return true
+	}
+
+	switch expr := expr.(type) {
+	case *ast.CallExpr:
+		return c.wrappedCall(expr)
+	case *ast.Ident:
+		if v := c.localVar(expr); v != nil {
+			return c.wrappedVar(v, seen)
+		}
+	}
+	return false
+}
+
+// wrappedVar reports whether every value the variable can hold is nil or
+// wrapped.
+func (c *checker) wrappedVar(v *types.Var, seen map[*types.Var]bool) bool {
+	if seen[v] {
+		return true
+	}
+	seen[v] = true
+	if c.opaque[v] || (len(c.sources[v]) == 0 && !c.zero[v]) {
+		return false
+	}
+	for _, src := range c.sources[v] {
+		if !c.wrapped(src, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// wrappedCall reports whether call is a call of a function from errgo or
+// from the package being analyzed.
+func (c *checker) wrappedCall(call *ast.CallExpr) bool {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return false
+	}
+	obj := c.pass.TypesInfo.Uses[ident]
+	if obj == nil || obj.Pkg() == nil {
+		return false
+	}
+	return obj.Pkg().Path() == errgoPath || obj.Pkg() == c.pass.Pkg
+}
+
+// errorResults returns the indexes of the results of sig with type error.
+func errorResults(sig *types.Signature) []int {
+	var positions []int
+	errorType := types.Universe.Lookup("error").Type()
+	for i := 0; i < sig.Results().Len(); i++ {
+		if types.Identical(sig.Results().At(i).Type(), errorType) {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+func isStackableError(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == errgoPath && obj.Name() == "StackableError"
+}
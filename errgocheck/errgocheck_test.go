@@ -0,0 +1,12 @@
+package errgocheck_test
+
+import (
+	"testing"
+
+	"github.com/freemish/errgo/errgocheck"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), errgocheck.Analyzer, "a")
+}
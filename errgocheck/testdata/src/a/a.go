@@ -0,0 +1,104 @@
+package a
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/freemish/errgo"
+)
+
+func helper() error { return errgo.Wrap("helper") }
+
+func pair() (int, error) { return 0, errgo.Wrap("pair") }
+
+func Plain() error {
+	return errors.New("plain") // want `error returned from exported Plain is not wrapped by errgo`
+}
+
+func Wrapped() error {
+	return errgo.Wrap(errors.New("wrapped"))
+}
+
+func WrapNil(err error) error {
+	return errgo.WrapNil(err)
+}
+
+func Nil() error {
+	return nil
+}
+
+func Typed() *errgo.StackableError {
+	return errgo.Wrap("typed")
+}
+
+func Helper() error {
+	return helper()
+}
+
+func HelperVar() error {
+	err := helper()
+	return err
+}
+
+func HelperPair() (int, error) {
+	n, err := pair()
+	return n, err
+}
+
+func HelperMulti() (int, error) {
+	return pair()
+}
+
+func PlainVar() error {
+	err := errors.New("plain")
+	return err // want `error returned from exported PlainVar is not wrapped by errgo`
+}
+
+func PlainPair(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err // want `error returned from exported PlainPair is not wrapped by errgo`
+	}
+	return n, nil
+}
+
+func PlainMulti(s string) (int, error) {
+	return strconv.Atoi(s) // want `error returned from exported PlainMulti is not wrapped by errgo`
+}
+
+func Param(err error) error {
+	return err // want `error returned from exported Param is not wrapped by errgo`
+}
+
+func Reassigned(fail bool) error {
+	err := helper()
+	if fail {
+		err = errors.New("reassigned")
+	}
+	return err // want `error returned from exported Reassigned is not wrapped by errgo`
+}
+
+func ZeroVar() error {
+	var err error
+	return err
+}
+
+func AddressTaken(target error) error {
+	var err error
+	errors.As(target, &err)
+	return err // want `error returned from exported AddressTaken is not wrapped by errgo`
+}
+
+func NamedHelper() (err error) {
+	err = helper()
+	return
+}
+
+func NamedPlain() (err error) {
+	err = errors.New("named")
+	return // want `error returned from exported NamedPlain is not wrapped by errgo`
+}
+
+func unexported() error {
+	return errors.New("not checked")
+}
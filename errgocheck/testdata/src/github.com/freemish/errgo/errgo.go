@@ -0,0 +1,10 @@
+// Package errgo is a stub of the real package for the analyzer tests.
+package errgo
+
+type StackableError struct{ Err error }
+
+func (err *StackableError) Error() string { return err.Err.Error() }
+
+func Wrap(e interface{}) *StackableError { return nil }
+
+func WrapNil(err error) error { return err }
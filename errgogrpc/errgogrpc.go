@@ -0,0 +1,129 @@
+// Package errgogrpc converts errgo errors to and from gRPC statuses,
+// carrying google.rpc error details such as ErrorInfo and BadRequest as
+// described by the AIP error model.
+package errgogrpc
+
+import (
+	"github.com/freemish/errgo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// AttachDetails wraps err like errgo.Wrap and attaches the given detail
+// messages, e.g. *errdetails.ErrorInfo or *errdetails.BadRequest, to be
+// sent along with its status by Status.
+func AttachDetails(err error, details ...proto.Message) *errgo.StackableError {
+	e := errgo.WrapSkip(err, 1)
+	for _, detail := range details {
+		e = e.AddDetail(detail)
+	}
+	return e
+}
+
+// ErrorInfo returns the first ErrorInfo detail attached to err.
+func ErrorInfo(err error) (*errdetails.ErrorInfo, bool) {
+	for _, detail := range details(err) {
+		if d, ok := detail.(*errdetails.ErrorInfo); ok {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// BadRequest returns the first BadRequest detail attached to err.
+func BadRequest(err error) (*errdetails.BadRequest, bool) {
+	for _, detail := range details(err) {
+		if d, ok := detail.(*errdetails.BadRequest); ok {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// RetryInfo returns the first RetryInfo detail attached to err.
+func RetryInfo(err error) (*errdetails.RetryInfo, bool) {
+	for _, detail := range details(err) {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// QuotaFailure returns the first QuotaFailure detail attached to err.
+func QuotaFailure(err error) (*errdetails.QuotaFailure, bool) {
+	for _, detail := range details(err) {
+		if d, ok := detail.(*errdetails.QuotaFailure); ok {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// details returns the proto details attached anywhere in the chain of err.
+func details(err error) []proto.Message {
+	var msgs []proto.Message
+	errs, _ := errgo.Chain(err)
+	for _, err := range errs {
+		e, ok := err.(*errgo.StackableError)
+		if !ok {
+			continue
+		}
+		for _, detail := range e.Details() {
+			if msg, ok := detail.(proto.Message); ok {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	return msgs
+}
+
+// Code returns the gRPC code for err, as given by errgo.GRPCCode: the
+// code registered for its errgo code, if any, and otherwise the code
+// matching its Kind.
+func Code(err error) codes.Code {
+	return codes.Code(errgo.GRPCCode(err))
+}
+
+// Status converts err to a gRPC status with the code given by Code, the
+// error message, and every detail message attached to the error chain.
+// A nil error gives an OK status.
+func Status(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+
+	st := status.New(Code(err), err.Error())
+	msgs := details(err)
+	if len(msgs) == 0 {
+		return st
+	}
+
+	v1 := make([]protoadapt.MessageV1, len(msgs))
+	for i, msg := range msgs {
+		v1[i] = protoadapt.MessageV1Of(msg)
+	}
+	if withDetails, detailErr := st.WithDetails(v1...); detailErr == nil {
+		return withDetails
+	}
+	return st
+}
+
+// FromStatus makes a StackableError from a gRPC status received from a
+// server, with the kind given by errgo.KindOfGRPCCode for its code and
+// the status details attached. An OK status gives nil.
+func FromStatus(st *status.Status) *errgo.StackableError {
+	if st.Code() == codes.OK {
+		return nil
+	}
+	e := errgo.ESkip(1, "", errgo.KindOfGRPCCode(int(st.Code())), st.Err())
+	for _, detail := range st.Details() {
+		if msg, ok := detail.(proto.Message); ok {
+			e = e.AddDetail(msg)
+		}
+	}
+	return e
+}
@@ -0,0 +1,84 @@
+package errgogrpc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatus(t *testing.T) {
+	info := &errdetails.ErrorInfo{Reason: "QUOTA", Domain: "example.com"}
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		details int
+	}{
+		{"nil", nil, codes.OK, 0},
+		{"plain", errors.New("boom"), codes.Unknown, 0},
+		{"kind", errgo.E("db.Get", errgo.NotExist, nil), codes.NotFound, 0},
+		{"details", AttachDetails(errgo.E("quota.Check", errgo.Transient, nil), info), codes.Unavailable, 1},
+		{"wrapped details", fmt.Errorf("api: %w", AttachDetails(errors.New("bad"), info)), codes.Unknown, 1},
+	}
+	for _, tt := range tests {
+		st := Status(tt.err)
+		if st.Code() != tt.code {
+			t.Errorf("%s: expected code %v, got %v", tt.name, tt.code, st.Code())
+		}
+		if len(st.Details()) != tt.details {
+			t.Errorf("%s: expected %d details, got %d", tt.name, tt.details, len(st.Details()))
+		}
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	if err := FromStatus(status.New(codes.OK, "")); err != nil {
+		t.Errorf("expected nil for an OK status, got %v", err)
+	}
+
+	tests := []struct {
+		code codes.Code
+		kind errgo.Kind
+	}{
+		{codes.InvalidArgument, errgo.Invalid},
+		{codes.NotFound, errgo.NotExist},
+		{codes.AlreadyExists, errgo.Exist},
+		{codes.PermissionDenied, errgo.Permission},
+		{codes.Unauthenticated, errgo.Permission},
+		{codes.Unavailable, errgo.Transient},
+		{codes.DeadlineExceeded, errgo.Transient},
+		{codes.Internal, errgo.Internal},
+		{codes.Unknown, errgo.Other},
+	}
+	for _, tt := range tests {
+		st, _ := status.New(tt.code, "failed").WithDetails(&errdetails.ErrorInfo{Reason: "R"})
+		err := FromStatus(st)
+		if err.Kind != tt.kind {
+			t.Errorf("%v: expected kind %v, got %v", tt.code, tt.kind, err.Kind)
+		}
+		if info, ok := ErrorInfo(err); !ok || info.Reason != "R" {
+			t.Errorf("%v: expected the ErrorInfo detail, got %v", tt.code, info)
+		}
+	}
+}
+
+func TestStackStartsAtCaller(t *testing.T) {
+	for name, err := range map[string]*errgo.StackableError{
+		"AttachDetails": AttachDetails(errors.New("x")),
+		"FromStatus":    FromStatus(status.New(codes.Internal, "x")),
+	} {
+		frames := err.StackFrames()
+		if len(frames) == 0 {
+			t.Skip("built without stacks")
+		}
+		if !strings.HasSuffix(frames[0].FunctionName, "TestStackStartsAtCaller") {
+			t.Errorf("%s: expected the stack to start at the test, got %s", name, frames[0].FunctionName)
+		}
+	}
+}
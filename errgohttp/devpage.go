@@ -0,0 +1,197 @@
+package errgohttp
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/freemish/errgo"
+)
+
+// SourceContext is the number of source lines shown above and below each
+// frame on the development error page.
+var SourceContext = 5
+
+// DevMiddleware recovers panics in next and answers them with the
+// development error page written by WriteDevPage. If next is a HandlerE,
+// the errors it returns are handled like DefaultErrorHandler does, but
+// answered with the same page. It shows source code and request headers,
+// so it must only be used in development.
+func DevMiddleware(next http.Handler) http.Handler {
+	if fn, ok := next.(HandlerE); ok {
+		dev := *DefaultErrorHandler
+		dev.Write = WriteDevError
+		next = dev.Handle(fn)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				WriteDevPage(w, r, errgo.Wrap(rec))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WriteDevPage responds with status 500 and an HTML page describing err:
+// every error in its chain with code, kind, fields and notes, each frame
+// of their stacks with the surrounding source, and the request. Frames
+// outside the standard library are expanded. A nil err gets a plain 500
+// response.
+func WriteDevPage(w http.ResponseWriter, r *http.Request, err error) {
+	WriteDevError(w, r, err, http.StatusInternalServerError)
+}
+
+// WriteDevError is WriteDevPage with the given status, for use as the
+// Write function of an ErrorHandler in development.
+func WriteDevError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	if err == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	page := devPage{Title: err.Error(), Method: r.Method, URL: r.URL.String(), RemoteAddr: r.RemoteAddr}
+	for name, values := range r.Header {
+		value := strings.Join(values, ", ")
+		if name == "Authorization" || name == "Cookie" {
+			value = "[redacted]"
+		}
+		page.Headers = append(page.Headers, errgo.Field{Key: name, Value: value})
+	}
+	sort.Slice(page.Headers, func(i, j int) bool { return page.Headers[i].Key < page.Headers[j].Key })
+
+	sources := map[string][]string{}
+	errs, _ := errgo.Chain(err)
+	for _, e := range errs {
+		layer := devLayer{Message: e.Error(), Type: fmt.Sprintf("%T", e)}
+		if e, ok := e.(*errgo.StackableError); ok {
+			layer.Code = e.Code
+			layer.Kind = e.Kind.String()
+			layer.Fields = e.Fields()
+			layer.Notes = e.Notes()
+			for _, frame := range e.StackFrames() {
+				layer.Frames = append(layer.Frames, newDevFrame(frame, sources))
+			}
+		}
+		page.Layers = append(page.Layers, layer)
+	}
+
+	var buf bytes.Buffer
+	if execErr := devTemplate.Execute(&buf, page); execErr != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+type devPage struct {
+	Title      string
+	Method     string
+	URL        string
+	RemoteAddr string
+	Headers    []errgo.Field
+	Layers     []devLayer
+}
+
+type devLayer struct {
+	Message string
+	Type    string
+	Code    string
+	Kind    string
+	Fields  []errgo.Field
+	Notes   []string
+	Frames  []devFrame
+}
+
+type devFrame struct {
+	errgo.StackFrame
+	URL    string
+	Stdlib bool
+	Source []devLine
+}
+
+type devLine struct {
+	Number  int
+	Text    string
+	Current bool
+}
+
+func newDevFrame(frame errgo.StackFrame, sources map[string][]string) devFrame {
+	f := devFrame{StackFrame: frame, URL: frame.SourceURL()}
+	first := strings.SplitN(frame.Package, "/", 2)[0]
+	f.Stdlib = !strings.Contains(first, ".")
+
+	lines, ok := sources[frame.File]
+	if !ok {
+		if data, err := os.ReadFile(frame.File); err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		sources[frame.File] = lines
+	}
+	for n := frame.LineNumber - SourceContext; n <= frame.LineNumber+SourceContext; n++ {
+		if n >= 1 && n <= len(lines) {
+			f.Source = append(f.Source, devLine{Number: n, Text: lines[n-1], Current: n == frame.LineNumber})
+		}
+	}
+	return f
+}
+
+var devTemplate = template.Must(template.New("dev").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { color: #b00; font-size: 1.4em; }
+section { border-left: 4px solid #b00; padding-left: 1em; margin-bottom: 2em; }
+details { margin: .3em 0; }
+summary { cursor: pointer; font-family: monospace; }
+pre { background: #f6f6f6; padding: .5em; overflow-x: auto; }
+.current { background: #fdd; display: block; }
+.stdlib summary { color: #888; }
+table { border-collapse: collapse; }
+td { padding: .1em 1em .1em 0; font-family: monospace; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range $i, $layer := .Layers}}
+<section>
+<h2>{{if $i}}Caused by: {{end}}{{$layer.Message}}</h2>
+<p><code>{{$layer.Type}}</code>{{if $layer.Code}} code <code>{{$layer.Code}}</code>{{end}}{{if $layer.Kind}} kind <code>{{$layer.Kind}}</code>{{end}}</p>
+{{if $layer.Fields}}<h3>Fields</h3>
+<table>{{range $layer.Fields}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>{{end}}</table>{{end}}
+{{if $layer.Notes}}<h3>Notes</h3>
+<ul>{{range $layer.Notes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if $layer.Frames}}<h3>Stack</h3>{{end}}
+{{range $layer.Frames}}
+<details{{if .Stdlib}} class="stdlib"{{else}} open{{end}}>
+<summary>{{.Package}}.{{.FunctionName}} {{.File}}:{{.LineNumber}}{{if .URL}} <a href="{{.URL}}">source</a>{{end}}</summary>
+{{if .Source}}<pre>{{range .Source}}<span{{if .Current}} class="current"{{end}}>{{printf "%5d" .Number}}  {{.Text}}</span>
+{{end}}</pre>{{end}}
+</details>
+{{end}}
+</section>
+{{end}}
+<section>
+<h2>Request</h2>
+<table>
+<tr><td>Method</td><td>{{.Method}}</td></tr>
+<tr><td>URL</td><td>{{.URL}}</td></tr>
+<tr><td>Remote address</td><td>{{.RemoteAddr}}</td></tr>
+{{range .Headers}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>{{end}}
+</table>
+</section>
+</body>
+</html>
+`))
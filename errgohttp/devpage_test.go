@@ -0,0 +1,55 @@
+package errgohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+func TestDevMiddleware(t *testing.T) {
+	cases := map[string]struct {
+		handler http.Handler
+		status  int
+		message string
+	}{
+		"returned error": {
+			handler: HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+				return errgo.E("users.Get", errgo.NotExist, nil, "user 1")
+			}),
+			status:  http.StatusNotFound,
+			message: "users.Get: user 1",
+		},
+		"panic": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			}),
+			status:  http.StatusInternalServerError,
+			message: "boom",
+		},
+	}
+
+	for name, c := range cases {
+		rec := httptest.NewRecorder()
+		DevMiddleware(c.handler).ServeHTTP(rec, httptest.NewRequest("GET", "/users/1", nil))
+		if rec.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d", name, c.status, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("%s: expected the HTML page, got %q", name, ct)
+		}
+		if !strings.Contains(rec.Body.String(), c.message) {
+			t.Errorf("%s: page does not mention %q", name, c.message)
+		}
+	}
+}
+
+func TestWriteDevPageNil(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteDevPage(rec, httptest.NewRequest("GET", "/", nil), nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,130 @@
+package errgohttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/freemish/errgo"
+)
+
+// HandlerE is an HTTP handler that returns its error instead of writing
+// it. As an http.Handler it passes errors to DefaultErrorHandler.
+type HandlerE func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP calls h and handles its error with DefaultErrorHandler.
+func (h HandlerE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	DefaultErrorHandler.Handle(h).ServeHTTP(w, r)
+}
+
+// ErrorHandler turns the errors returned by HandlerE functions into
+// responses, so handlers don't each have to log, map and write them.
+type ErrorHandler struct {
+	// Reporter, if set, receives errors answered with a 5xx status.
+	Reporter errgo.Reporter
+
+	// Status maps an error to the response status. If nil, StatusCode is
+	// used.
+	Status func(err error) int
+
+	// Write writes the response for err. If nil, WriteError is used.
+	Write func(w http.ResponseWriter, r *http.Request, err error, status int)
+}
+
+// DefaultErrorHandler is used by HandlerE.ServeHTTP.
+var DefaultErrorHandler = &ErrorHandler{}
+
+// Handle adapts fn to an http.Handler. An error returned by fn is wrapped
+// with errgo.WrapContext to pick up the request's breadcrumbs, mapped to
+// a status, reported if it is a server error, and written, unless fn had
+// already started the response.
+func (h *ErrorHandler) Handle(fn HandlerE) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		err := fn(rw, r)
+		if err == nil {
+			return
+		}
+		e := errgo.WrapContext(r.Context(), err)
+
+		statusFn := h.Status
+		if statusFn == nil {
+			statusFn = StatusCode
+		}
+		status := statusFn(e)
+		if status >= 500 && h.Reporter != nil {
+			h.Reporter.Report(r.Context(), e)
+		}
+		if rw.wrote {
+			return
+		}
+
+		write := h.Write
+		if write == nil {
+			write = WriteError
+		}
+		write(w, r, e, status)
+	})
+}
+
+// StatusCode returns the HTTP status for err, as given by
+// errgo.HTTPStatus: the status registered for its errgo code, if any, and
+// otherwise the status matching its Kind.
+func StatusCode(err error) int {
+	return errgo.HTTPStatus(err)
+}
+
+// errorBody is the JSON response written by WriteError.
+type errorBody struct {
+	Error struct {
+		Code       string                 `json:"code,omitempty"`
+		Message    string                 `json:"message"`
+		Violations []errgo.FieldViolation `json:"violations,omitempty"`
+	} `json:"error"`
+}
+
+// WriteError writes err as a JSON object with its code, message and any
+// validation violations. The messages of server errors are replaced with
+// the status text, so internal details do not leak to clients.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	var body errorBody
+	body.Error.Message = err.Error()
+	if status >= 500 {
+		body.Error.Message = http.StatusText(status)
+	}
+	errs, _ := errgo.Chain(err)
+	for _, err := range errs {
+		if e, ok := err.(*errgo.StackableError); ok && e.Code != "" {
+			body.Error.Code = e.Code
+			break
+		}
+	}
+	if v, ok := errgo.AsValidationError(err); ok {
+		body.Error.Violations = v.Violations
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// responseWriter records whether the handler started the response.
+type responseWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original ResponseWriter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
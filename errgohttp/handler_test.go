@@ -0,0 +1,128 @@
+package errgohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+func TestHandle(t *testing.T) {
+	coded := errgo.E("users.Get", errgo.NotExist, nil)
+	coded.Code = "user_missing"
+
+	cases := []struct {
+		name     string
+		fn       HandlerE
+		status   int
+		body     string
+		reported bool
+	}{
+		{
+			"no error",
+			func(w http.ResponseWriter, r *http.Request) error { w.Write([]byte("ok")); return nil },
+			http.StatusOK,
+			"ok",
+			false,
+		},
+		{
+			"client error",
+			func(w http.ResponseWriter, r *http.Request) error { return coded },
+			http.StatusNotFound,
+			`{"error":{"code":"user_missing","message":"users.Get: item does not exist"}}` + "\n",
+			false,
+		},
+		{
+			"validation",
+			func(w http.ResponseWriter, r *http.Request) error {
+				return errgo.Validation(errgo.FieldViolation{Field: "name", Message: "is required"})
+			},
+			http.StatusBadRequest,
+			`{"error":{"message":"invalid input: name: is required","violations":[{"field":"name","message":"is required"}]}}` + "\n",
+			false,
+		},
+		{
+			"server error",
+			func(w http.ResponseWriter, r *http.Request) error { return errors.New("db password rejected") },
+			http.StatusInternalServerError,
+			`{"error":{"message":"Internal Server Error"}}` + "\n",
+			true,
+		},
+		{
+			"response started",
+			func(w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusAccepted)
+				return errors.New("lost connection")
+			},
+			http.StatusAccepted,
+			"",
+			true,
+		},
+	}
+	for _, c := range cases {
+		var reported []error
+		h := &ErrorHandler{Reporter: errgo.ReporterFunc(func(ctx context.Context, err error) {
+			reported = append(reported, err)
+		})}
+
+		rec := httptest.NewRecorder()
+		h.Handle(c.fn).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d", c.name, c.status, rec.Code)
+		}
+		if rec.Body.String() != c.body {
+			t.Errorf("%s: expected body %q, got %q", c.name, c.body, rec.Body.String())
+		}
+		if (len(reported) > 0) != c.reported {
+			t.Errorf("%s: expected reported %v, got %v", c.name, c.reported, reported)
+		}
+	}
+}
+
+func TestHandleOverrides(t *testing.T) {
+	h := &ErrorHandler{
+		Status: func(err error) int { return http.StatusTeapot },
+		Write: func(w http.ResponseWriter, r *http.Request, err error, status int) {
+			w.WriteHeader(status)
+			w.Write([]byte("custom: " + err.Error()))
+		},
+	}
+	fn := func(w http.ResponseWriter, r *http.Request) error { return errors.New("boom") }
+
+	rec := httptest.NewRecorder()
+	h.Handle(fn).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusTeapot || rec.Body.String() != "custom: boom" {
+		t.Errorf("expected the custom status and writer to be used, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerEBreadcrumbs(t *testing.T) {
+	defer func(h *ErrorHandler) { DefaultErrorHandler = h }(DefaultErrorHandler)
+	var reported *errgo.StackableError
+	DefaultErrorHandler = &ErrorHandler{Reporter: errgo.ReporterFunc(func(ctx context.Context, err error) {
+		reported, _ = err.(*errgo.StackableError)
+	})}
+
+	handler := HandlerE(func(w http.ResponseWriter, r *http.Request) error {
+		errgo.AddBreadcrumb(r.Context(), "loaded user")
+		return errors.New("boom")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(errgo.WithBreadcrumbs(req.Context()))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rec.Code)
+	}
+	if reported == nil || len(reported.Breadcrumbs()) != 1 || !strings.Contains(reported.StackTrace(), "loaded user") {
+		t.Errorf("expected the reported error to carry the request's breadcrumbs, got %v", reported)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON response, got %q", rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,119 @@
+package errgohttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/freemish/errgo"
+)
+
+// kindName returns the name of the kind's constant, used for component
+// names.
+func kindName(kind errgo.Kind) string {
+	if name := kind.Name(); name != "" {
+		return name
+	}
+	return fmt.Sprint(int(kind))
+}
+
+var componentName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// WriteOpenAPI writes, as JSON, the OpenAPI 3 components describing the
+// error responses written by WriteError: an Error schema with the
+// registered codes, if any, as an enum, and a response for every registered code
+// and every kind, each under the status StatusCode maps it to. The
+// responses can be referenced from an API description as, e.g.,
+// "#/components/responses/user_not_found" or
+// "#/components/responses/Kind.NotExist". Like WriteCatalogMarkdown, it is
+// usually run by go:generate from a program that imports the packages
+// declaring the codes.
+func WriteOpenAPI(w io.Writer) error {
+	defs := errgo.Definitions()
+	codes := make([]string, len(defs))
+	for i, def := range defs {
+		codes[i] = def.Code
+	}
+
+	errorSchema := object(map[string]interface{}{
+		"error": object(map[string]interface{}{
+			"code":    map[string]interface{}{"$ref": "#/components/schemas/ErrorCode"},
+			"message": map[string]interface{}{"type": "string"},
+			"violations": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"$ref": "#/components/schemas/FieldViolation"},
+			},
+		}, "message"),
+	}, "error")
+
+	responses := map[string]interface{}{}
+	for _, def := range defs {
+		status := def.HTTPStatus
+		if status == 0 {
+			status = def.Kind.HTTPStatus()
+		}
+		message := def.Message
+		if status >= 500 {
+			message = http.StatusText(status)
+		}
+		responses[componentName.ReplaceAllString(def.Code, "_")] = response(def.Message, status, kindName(def.Kind), def.Code, message)
+	}
+	for _, kind := range errgo.Kinds() {
+		status := kind.HTTPStatus()
+		responses["Kind."+kindName(kind)] = response(kind.String(), status, kindName(kind), "", http.StatusText(status))
+	}
+
+	codeSchema := map[string]interface{}{"type": "string"}
+	if len(codes) > 0 {
+		codeSchema["enum"] = codes
+	}
+
+	doc := map[string]interface{}{
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"ErrorCode": codeSchema,
+				"Error":     errorSchema,
+				"FieldViolation": object(map[string]interface{}{
+					"field":   map[string]interface{}{"type": "string"},
+					"code":    map[string]interface{}{"type": "string"},
+					"message": map[string]interface{}{"type": "string"},
+				}, "field", "message"),
+			},
+			"responses": responses,
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func object(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// response describes one error response. The x-http-status and
+// x-errgo-kind extensions record the mapping used at runtime.
+func response(description string, status int, kind, code, message string) map[string]interface{} {
+	example := map[string]interface{}{"message": message}
+	if code != "" {
+		example["code"] = code
+	}
+	return map[string]interface{}{
+		"description":   fmt.Sprintf("%d %s: %s", status, http.StatusText(status), description),
+		"x-http-status": status,
+		"x-errgo-kind":  kind,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema":  map[string]interface{}{"$ref": "#/components/schemas/Error"},
+				"example": map[string]interface{}{"error": example},
+			},
+		},
+	}
+}
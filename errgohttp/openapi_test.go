@@ -0,0 +1,57 @@
+package errgohttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+func TestWriteOpenAPI(t *testing.T) {
+	type doc struct {
+		Components struct {
+			Schemas struct {
+				ErrorCode map[string]interface{}
+			}
+			Responses map[string]map[string]interface{}
+		}
+	}
+	write := func() doc {
+		var buf bytes.Buffer
+		if err := WriteOpenAPI(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var d doc
+		if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	d := write()
+	if _, ok := d.Components.Schemas.ErrorCode["enum"]; ok && len(errgo.Definitions()) == 0 {
+		t.Errorf("expected no enum without registered codes, got %v", d.Components.Schemas.ErrorCode)
+	}
+	for _, kind := range errgo.Kinds() {
+		r, ok := d.Components.Responses["Kind."+kind.Name()]
+		if !ok {
+			t.Errorf("missing response for kind %s", kind.Name())
+			continue
+		}
+		if status := r["x-http-status"]; status != float64(kind.HTTPStatus()) {
+			t.Errorf("kind %s: expected status %d, got %v", kind.Name(), kind.HTTPStatus(), status)
+		}
+	}
+
+	if _, ok := errgo.Lookup("openapi_test.gone"); !ok {
+		errgo.Register(errgo.Definition{Code: "openapi_test.gone", Message: "gone", Kind: errgo.NotExist})
+	}
+	d = write()
+	if enum, _ := d.Components.Schemas.ErrorCode["enum"].([]interface{}); len(enum) != 1 || enum[0] != "openapi_test.gone" {
+		t.Errorf("expected the registered code in the enum, got %v", d.Components.Schemas.ErrorCode)
+	}
+	if r := d.Components.Responses["openapi_test.gone"]; r["x-errgo-kind"] != "NotExist" || r["x-http-status"] != float64(404) {
+		t.Errorf("unexpected response for the registered code: %v", r)
+	}
+}
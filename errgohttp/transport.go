@@ -0,0 +1,136 @@
+// Package errgohttp adds errgo stacks and context to errors on both sides
+// of net/http.
+package errgohttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/freemish/errgo"
+)
+
+// Failure classes recorded in the "http.class" field of transport errors.
+const (
+	ClassDNS        = "dns"
+	ClassTLS        = "tls"
+	ClassTimeout    = "timeout"
+	ClassConnection = "connection"
+	ClassCanceled   = "canceled"
+	ClassOther      = "other"
+)
+
+// Transport is an http.RoundTripper that turns the errors of Base into
+// StackableErrors with the fields http.method, http.url, http.duration and
+// http.class, and a kind derived from the class. Failures to reach the
+// server, of any class but ClassCanceled and ClassOther, are retryable for
+// errgo.IsRetryable. Responses, including those with error statuses, are
+// passed through unchanged.
+type Transport struct {
+	// Base is the RoundTripper used to make requests. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := errgo.Now()
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		return resp, nil
+	}
+
+	class := Classify(err)
+	if class != ClassOther {
+		err = &transportError{err, class != ClassCanceled}
+	}
+	e := errgo.E("", kindOf(class), err)
+	e = e.WithField("http.method", req.Method).
+		WithField("http.url", req.URL.Redacted()).
+		WithField("http.duration", errgo.Now().Sub(start)).
+		WithField("http.class", class)
+	return resp, e
+}
+
+// transportError marks a failure to reach the server as retryable, since
+// the net package reports failed lookups and certificate errors as not
+// temporary, and a canceled request as not retryable, since
+// context.DeadlineExceeded reports itself as temporary.
+type transportError struct {
+	err       error
+	retryable bool
+}
+
+func (e *transportError) Error() string   { return e.err.Error() }
+func (e *transportError) Unwrap() error   { return e.err }
+func (e *transportError) Retryable() bool { return e.retryable }
+
+// CloseIdleConnections closes the idle connections of Base, if it
+// supports that, so http.Client.CloseIdleConnections works through the
+// Transport.
+func (t *Transport) CloseIdleConnections() {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if closer, ok := base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Classify returns the class of a transport error: ClassDNS for failed
+// lookups, ClassTLS for handshake and certificate errors, ClassTimeout,
+// ClassConnection for refused or reset connections, ClassCanceled when the
+// request's context was canceled or its deadline passed, and ClassOther.
+// A passed context deadline is not a timeout of the server, so it is not
+// retried.
+func Classify(err error) string {
+	var (
+		dnsErr     *net.DNSError
+		recordErr  tls.RecordHeaderError
+		verifyErr  *tls.CertificateVerificationError
+		unknownCA  x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+		netErr     net.Error
+	)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ClassCanceled
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return ClassTimeout
+		}
+		return ClassDNS
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr),
+		errors.As(err, &unknownCA), errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return ClassTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return ClassConnection
+	}
+	return ClassOther
+}
+
+// kindOf returns the kind for a transport error of the given class. DNS
+// and TLS failures are IO errors, like any other failure to reach the
+// server, so they are served as 5xx and stay retryable.
+func kindOf(class string) errgo.Kind {
+	switch class {
+	case ClassDNS, ClassTLS:
+		return errgo.IO
+	case ClassTimeout, ClassConnection:
+		return errgo.Transient
+	}
+	return errgo.Other
+}
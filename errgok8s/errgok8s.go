@@ -0,0 +1,141 @@
+// Package errgok8s connects errgo errors with the error types of
+// k8s.io/apimachinery, for controllers and admission webhooks built on
+// errgo.
+package errgok8s
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/freemish/errgo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// StatusError returns the first API status error in the chain of err.
+func StatusError(err error) (*apierrors.StatusError, bool) {
+	var status *apierrors.StatusError
+	ok := errors.As(err, &status)
+	return status, ok
+}
+
+// Reason returns the reason of the first API status error in the chain of
+// err, or metav1.StatusReasonUnknown if there is none.
+func Reason(err error) metav1.StatusReason {
+	if status, ok := StatusError(err); ok {
+		return status.ErrStatus.Reason
+	}
+	return metav1.StatusReasonUnknown
+}
+
+// HTTPCode returns the HTTP code of the first API status error in the
+// chain of err, or 0 if there is none.
+func HTTPCode(err error) int32 {
+	if status, ok := StatusError(err); ok {
+		return status.ErrStatus.Code
+	}
+	return 0
+}
+
+// Aggregate returns the first apimachinery Aggregate in the chain of err,
+// as returned by utilerrors.NewAggregate and many validation helpers.
+func Aggregate(err error) (utilerrors.Aggregate, bool) {
+	var agg utilerrors.Aggregate
+	ok := errors.As(err, &agg)
+	return agg, ok
+}
+
+// KindOfReason returns the errgo Kind matching the reason of an API
+// status error.
+func KindOfReason(reason metav1.StatusReason) errgo.Kind {
+	switch reason {
+	case metav1.StatusReasonBadRequest, metav1.StatusReasonInvalid,
+		metav1.StatusReasonMethodNotAllowed, metav1.StatusReasonNotAcceptable,
+		metav1.StatusReasonRequestEntityTooLarge, metav1.StatusReasonUnsupportedMediaType:
+		return errgo.Invalid
+	case metav1.StatusReasonUnauthorized, metav1.StatusReasonForbidden:
+		return errgo.Permission
+	case metav1.StatusReasonAlreadyExists, metav1.StatusReasonConflict:
+		return errgo.Exist
+	case metav1.StatusReasonNotFound, metav1.StatusReasonGone:
+		return errgo.NotExist
+	case metav1.StatusReasonServerTimeout, metav1.StatusReasonTimeout,
+		metav1.StatusReasonTooManyRequests, metav1.StatusReasonServiceUnavailable:
+		return errgo.Transient
+	case metav1.StatusReasonInternalError:
+		return errgo.Internal
+	}
+	return errgo.Other
+}
+
+// Converter gives API status errors the errgo Kind matching their reason
+// when they are wrapped. Install it with errgo.RegisterConverter.
+func Converter(err error) (*errgo.StackableError, bool) {
+	status, ok := StatusError(err)
+	if !ok {
+		return nil, false
+	}
+	return &errgo.StackableError{Err: err, Kind: KindOfReason(status.ErrStatus.Reason)}, true
+}
+
+// ToStatusError converts err into an API status error to return from an
+// API server or admission webhook. An API status error already in the
+// chain is returned as is. Otherwise the HTTP status registered for the
+// error's code, if any, or else its Kind, decides the code and reason,
+// and the violations of a ValidationError become the status causes.
+func ToStatusError(err error) *apierrors.StatusError {
+	if status, ok := StatusError(err); ok {
+		return status
+	}
+
+	status := metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+	}
+	if v, ok := errgo.AsValidationError(err); ok {
+		status.Code = http.StatusUnprocessableEntity
+		status.Reason = metav1.StatusReasonInvalid
+		status.Details = &metav1.StatusDetails{}
+		for _, violation := range v.Violations {
+			status.Details.Causes = append(status.Details.Causes, metav1.StatusCause{
+				Type:    metav1.CauseTypeFieldValueInvalid,
+				Message: violation.Message,
+				Field:   violation.Field,
+			})
+		}
+		return &apierrors.StatusError{ErrStatus: status}
+	}
+
+	status.Code = int32(errgo.HTTPStatus(err))
+	status.Reason = reasonForCode(status.Code)
+	return &apierrors.StatusError{ErrStatus: status}
+}
+
+func reasonForCode(code int32) metav1.StatusReason {
+	switch code {
+	case http.StatusBadRequest:
+		return metav1.StatusReasonBadRequest
+	case http.StatusUnauthorized:
+		return metav1.StatusReasonUnauthorized
+	case http.StatusForbidden:
+		return metav1.StatusReasonForbidden
+	case http.StatusNotFound:
+		return metav1.StatusReasonNotFound
+	case http.StatusConflict:
+		return metav1.StatusReasonAlreadyExists
+	case http.StatusGone:
+		return metav1.StatusReasonGone
+	case http.StatusUnprocessableEntity:
+		return metav1.StatusReasonInvalid
+	case http.StatusTooManyRequests:
+		return metav1.StatusReasonTooManyRequests
+	case http.StatusServiceUnavailable:
+		return metav1.StatusReasonServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return metav1.StatusReasonTimeout
+	case http.StatusInternalServerError:
+		return metav1.StatusReasonInternalError
+	}
+	return metav1.StatusReasonUnknown
+}
@@ -0,0 +1,120 @@
+package errgok8s
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/freemish/errgo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func statusError(reason metav1.StatusReason, code int32) *apierrors.StatusError {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: string(reason),
+		Reason:  reason,
+		Code:    code,
+	}}
+}
+
+func TestStatusError(t *testing.T) {
+	notFound := statusError(metav1.StatusReasonNotFound, http.StatusNotFound)
+	cases := []struct {
+		name   string
+		err    error
+		reason metav1.StatusReason
+		code   int32
+	}{
+		{"status error", notFound, metav1.StatusReasonNotFound, http.StatusNotFound},
+		{"wrapped", errgo.WrapPrefix(fmt.Errorf("get pod: %w", notFound), "reconcile"), metav1.StatusReasonNotFound, http.StatusNotFound},
+		{"plain", errors.New("boom"), metav1.StatusReasonUnknown, 0},
+	}
+	for _, c := range cases {
+		if reason := Reason(c.err); reason != c.reason {
+			t.Errorf("%s: expected reason %q, got %q", c.name, c.reason, reason)
+		}
+		if code := HTTPCode(c.err); code != c.code {
+			t.Errorf("%s: expected code %d, got %d", c.name, c.code, code)
+		}
+	}
+}
+
+func TestKindOfReason(t *testing.T) {
+	cases := []struct {
+		reason metav1.StatusReason
+		kind   errgo.Kind
+	}{
+		{metav1.StatusReasonInvalid, errgo.Invalid},
+		{metav1.StatusReasonForbidden, errgo.Permission},
+		{metav1.StatusReasonConflict, errgo.Exist},
+		{metav1.StatusReasonGone, errgo.NotExist},
+		{metav1.StatusReasonTooManyRequests, errgo.Transient},
+		{metav1.StatusReasonInternalError, errgo.Internal},
+		{metav1.StatusReasonUnknown, errgo.Other},
+	}
+	for _, c := range cases {
+		if kind := KindOfReason(c.reason); kind != c.kind {
+			t.Errorf("%q: expected %v, got %v", c.reason, c.kind, kind)
+		}
+	}
+}
+
+func TestConverter(t *testing.T) {
+	if _, ok := Converter(errors.New("boom")); ok {
+		t.Error("converted an error that is not an API status error")
+	}
+	e, ok := Converter(fmt.Errorf("get: %w", statusError(metav1.StatusReasonConflict, http.StatusConflict)))
+	if !ok || e.Kind != errgo.Exist {
+		t.Errorf("expected a StackableError of kind Exist, got %v", e)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	agg := utilerrors.NewAggregate([]error{errors.New("a"), errors.New("b")})
+	if found, ok := Aggregate(errgo.Wrap(agg)); !ok || len(found.Errors()) != 2 {
+		t.Errorf("expected the aggregate, got %v", found)
+	}
+	if _, ok := Aggregate(errors.New("a")); ok {
+		t.Error("found an aggregate in a plain error")
+	}
+}
+
+func TestToStatusError(t *testing.T) {
+	existing := statusError(metav1.StatusReasonForbidden, http.StatusForbidden)
+	cases := []struct {
+		name   string
+		err    error
+		code   int32
+		reason metav1.StatusReason
+		causes int
+	}{
+		{"status error", errgo.Wrap(existing), http.StatusForbidden, metav1.StatusReasonForbidden, 0},
+		{"kind", errgo.E("db.Get", errgo.NotExist, nil), http.StatusNotFound, metav1.StatusReasonNotFound, 0},
+		{"plain", errors.New("boom"), http.StatusInternalServerError, metav1.StatusReasonInternalError, 0},
+		{
+			"validation",
+			errgo.Validation(
+				errgo.FieldViolation{Field: "spec.replicas", Message: "must be positive"},
+				errgo.FieldViolation{Field: "spec.image", Message: "required"},
+			),
+			http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, 2,
+		},
+	}
+	for _, c := range cases {
+		status := ToStatusError(c.err).ErrStatus
+		if status.Code != c.code || status.Reason != c.reason {
+			t.Errorf("%s: expected %d %q, got %d %q", c.name, c.code, c.reason, status.Code, status.Reason)
+		}
+		causes := 0
+		if status.Details != nil {
+			causes = len(status.Details.Causes)
+		}
+		if causes != c.causes {
+			t.Errorf("%s: expected %d causes, got %d", c.name, c.causes, causes)
+		}
+	}
+}
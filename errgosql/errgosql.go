@@ -0,0 +1,420 @@
+// Package errgosql wraps a database/sql driver so that every error it
+// returns from connecting, preparing, querying, executing and committing
+// becomes a StackableError with the caller's stack and an Op naming the
+// SQL operation, such as "sql.query" or "sql.commit".
+package errgosql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/freemish/errgo"
+)
+
+// Open is like sql.Open, but wraps the driver registered as driverName.
+func Open(driverName, dataSourceName string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, errgo.Wrap(err)
+	}
+	d := db.Driver()
+	db.Close()
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dataSourceName)
+		if err != nil {
+			return nil, wrapErr("open", err)
+		}
+		return sql.OpenDB(WrapConnector(connector)), nil
+	}
+	return sql.OpenDB(&dsnConnector{dsn: dataSourceName, driver: d}), nil
+}
+
+// Wrap returns a driver that wraps the errors of d. It can be registered
+// with sql.Register under a new name.
+func Wrap(d driver.Driver) driver.Driver {
+	return &wrappedDriver{d}
+}
+
+// WrapConnector returns a connector that wraps the errors of c, for use
+// with sql.OpenDB.
+func WrapConnector(c driver.Connector) driver.Connector {
+	return &wrappedConnector{c}
+}
+
+// wrapErr wraps err for operation op. Sentinel errors that database/sql
+// compares by identity, and io.EOF at the end of rows, are returned as is.
+func wrapErr(op string, err error) error {
+	if err == nil || err == io.EOF || err == driver.ErrSkip || err == driver.ErrRemoveArgument {
+		return err
+	}
+	return errgo.E(errgo.Op("sql."+op), errgo.Other, err)
+}
+
+type wrappedDriver struct {
+	driver.Driver
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, wrapErr("connect", err)
+	}
+	return &wrappedConn{conn}, nil
+}
+
+type wrappedConnector struct {
+	connector driver.Connector
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, wrapErr("connect", err)
+	}
+	return &wrappedConn{conn}, nil
+}
+
+func (c *wrappedConnector) Driver() driver.Driver {
+	return Wrap(c.connector.Driver())
+}
+
+// dsnConnector is the connector sql.Open would use for a driver without
+// OpenConnector.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, wrapErr("connect", err)
+	}
+	return &wrappedConn{conn}, nil
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return Wrap(c.driver)
+}
+
+type wrappedConn struct {
+	conn driver.Conn
+}
+
+func (c *wrappedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if pc, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, wrapErr("prepare", err)
+	}
+	return wrapStmt(stmt), nil
+}
+
+func (c *wrappedConn) Close() error {
+	return wrapErr("close", c.conn.Close())
+}
+
+func (c *wrappedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	var err error
+	if bc, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = bc.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("errgosql: driver does not support transaction options")
+	} else {
+		tx, err = c.conn.Begin()
+	}
+	if err != nil {
+		return nil, wrapErr("begin", err)
+	}
+	return &wrappedTx{tx}, nil
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	result, err := ec.ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, wrapErr("exec", err)
+	}
+	return &wrappedResult{result}, nil
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := qc.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, wrapErr("query", err)
+	}
+	return wrapRows(rows), nil
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return wrapErr("ping", p.Ping(ctx))
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type wrappedStmt struct {
+	stmt driver.Stmt
+}
+
+func (s *wrappedStmt) Close() error {
+	return wrapErr("close", s.stmt.Close())
+}
+
+func (s *wrappedStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *wrappedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	result, err := s.stmt.Exec(args)
+	if err != nil {
+		return nil, wrapErr("exec", err)
+	}
+	return &wrappedResult{result}, nil
+}
+
+func (s *wrappedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.stmt.Query(args)
+	if err != nil {
+		return nil, wrapErr("query", err)
+	}
+	return wrapRows(rows), nil
+}
+
+func (s *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	sc, ok := s.stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedValuesToValues(args)
+		if err != nil {
+			return nil, wrapErr("exec", err)
+		}
+		return s.Exec(values)
+	}
+	result, err := sc.ExecContext(ctx, args)
+	if err != nil {
+		return nil, wrapErr("exec", err)
+	}
+	return &wrappedResult{result}, nil
+}
+
+func (s *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	sc, ok := s.stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedValuesToValues(args)
+		if err != nil {
+			return nil, wrapErr("query", err)
+		}
+		return s.Query(values)
+	}
+	rows, err := sc.QueryContext(ctx, args)
+	if err != nil {
+		return nil, wrapErr("query", err)
+	}
+	return wrapRows(rows), nil
+}
+
+// wrapStmt wraps stmt in a type that has the same optional argument
+// conversion interfaces, since database/sql behaves differently depending
+// on which of them a statement implements.
+func wrapStmt(stmt driver.Stmt) driver.Stmt {
+	s := &wrappedStmt{stmt}
+	_, checker := stmt.(driver.NamedValueChecker)
+	_, converter := stmt.(driver.ColumnConverter)
+	switch {
+	case checker && converter:
+		return &struct {
+			*wrappedStmt
+			namedValueCheckerStmt
+			columnConverterStmt
+		}{s, namedValueCheckerStmt{s}, columnConverterStmt{s}}
+	case checker:
+		return &struct {
+			*wrappedStmt
+			namedValueCheckerStmt
+		}{s, namedValueCheckerStmt{s}}
+	case converter:
+		return &struct {
+			*wrappedStmt
+			columnConverterStmt
+		}{s, columnConverterStmt{s}}
+	}
+	return s
+}
+
+type namedValueCheckerStmt struct {
+	s *wrappedStmt
+}
+
+func (c namedValueCheckerStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	return c.s.stmt.(driver.NamedValueChecker).CheckNamedValue(nv)
+}
+
+type columnConverterStmt struct {
+	s *wrappedStmt
+}
+
+func (c columnConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return c.s.stmt.(driver.ColumnConverter).ColumnConverter(idx)
+}
+
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("errgosql: driver does not support named parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
+
+type wrappedTx struct {
+	tx driver.Tx
+}
+
+func (t *wrappedTx) Commit() error {
+	return wrapErr("commit", t.tx.Commit())
+}
+
+func (t *wrappedTx) Rollback() error {
+	return wrapErr("rollback", t.tx.Rollback())
+}
+
+type wrappedResult struct {
+	result driver.Result
+}
+
+func (r *wrappedResult) LastInsertId() (int64, error) {
+	id, err := r.result.LastInsertId()
+	return id, wrapErr("last_insert_id", err)
+}
+
+func (r *wrappedResult) RowsAffected() (int64, error) {
+	n, err := r.result.RowsAffected()
+	return n, wrapErr("rows_affected", err)
+}
+
+// wrapRows wraps rows, adding the multiple result set methods if rows has
+// them.
+func wrapRows(rows driver.Rows) driver.Rows {
+	r := &wrappedRows{rows}
+	if _, ok := rows.(driver.RowsNextResultSet); ok {
+		return &wrappedRowsNextResultSet{r}
+	}
+	return r
+}
+
+// wrappedRows wraps the errors of rows. The column type methods return
+// what database/sql assumes for rows that lack them, so forwarding them
+// unconditionally does not change behavior.
+type wrappedRows struct {
+	rows driver.Rows
+}
+
+var scanTypeAny = reflect.TypeOf((*interface{})(nil)).Elem()
+
+func (r *wrappedRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return scanTypeAny
+}
+
+func (r *wrappedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *wrappedRows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *wrappedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *wrappedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+type wrappedRowsNextResultSet struct {
+	*wrappedRows
+}
+
+func (r *wrappedRowsNextResultSet) HasNextResultSet() bool {
+	return r.rows.(driver.RowsNextResultSet).HasNextResultSet()
+}
+
+func (r *wrappedRowsNextResultSet) NextResultSet() error {
+	return wrapErr("next_result_set", r.rows.(driver.RowsNextResultSet).NextResultSet())
+}
+
+func (r *wrappedRows) Columns() []string {
+	return r.rows.Columns()
+}
+
+func (r *wrappedRows) Close() error {
+	return wrapErr("close", r.rows.Close())
+}
+
+func (r *wrappedRows) Next(dest []driver.Value) error {
+	return wrapErr("next", r.rows.Next(dest))
+}
@@ -0,0 +1,161 @@
+package errgosql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+// fakeDriver answers "fail" with an error and anything else with two
+// result sets of one row each. Statements convert every argument to a
+// string through ColumnConverter.
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct{ query string }
+
+type fakeRows struct {
+	set, row int
+}
+
+type stringConverter struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query == "fail" {
+		return nil, errors.New("exec failed")
+	}
+	if len(args) > 0 {
+		if _, ok := args[0].(string); !ok {
+			return nil, fmt.Errorf("argument was not converted: %T", args[0])
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.query == "fail" {
+		return nil, errors.New("query failed")
+	}
+	return &fakeRows{}, nil
+}
+
+func (s *fakeStmt) ColumnConverter(idx int) driver.ValueConverter { return stringConverter{} }
+
+func (stringConverter) ConvertValue(v interface{}) (driver.Value, error) { return fmt.Sprint(v), nil }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.row > 0 {
+		return io.EOF
+	}
+	r.row++
+	dest[0] = int64(r.set)
+	return nil
+}
+
+func (r *fakeRows) HasNextResultSet() bool { return r.set == 0 }
+
+func (r *fakeRows) NextResultSet() error {
+	if r.set > 0 {
+		return io.EOF
+	}
+	r.set, r.row = r.set+1, 0
+	return nil
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(index int) string { return "BIGINT" }
+
+func init() {
+	sql.Register("errgosql_fake", fakeDriver{})
+}
+
+func open(t *testing.T) *sql.DB {
+	db, err := Open("errgosql_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestErrorsAreWrapped(t *testing.T) {
+	db := open(t)
+
+	_, err := db.Exec("fail")
+	var e *errgo.StackableError
+	if !errors.As(err, &e) || e.Op != "sql.exec" {
+		t.Fatalf("expected a StackableError with op sql.exec, got %#v", err)
+	}
+	if len(e.Callers()) > 0 && !strings.Contains(e.Stack(), "TestErrorsAreWrapped") {
+		t.Errorf("expected the caller in the stack, got:\n%s", e.Stack())
+	}
+
+	if _, err := db.Query("fail"); !errors.As(err, &e) || e.Op != "sql.query" {
+		t.Errorf("expected a StackableError with op sql.query, got %#v", err)
+	}
+	if _, err := db.Begin(); !errors.As(err, &e) || e.Op != "sql.begin" {
+		t.Errorf("expected a StackableError with op sql.begin, got %#v", err)
+	}
+}
+
+func TestColumnConverterForwarded(t *testing.T) {
+	db := open(t)
+	if _, err := db.Exec("insert", 42); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRowsInterfacesForwarded(t *testing.T) {
+	db := open(t)
+	rows, err := db.Query("select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := types[0].DatabaseTypeName(); name != "BIGINT" {
+		t.Errorf("expected database type BIGINT, got %q", name)
+	}
+
+	var sets []int64
+	for {
+		for rows.Next() {
+			var n int64
+			if err := rows.Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			sets = append(sets, n)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sets) != "[0 1]" {
+		t.Errorf("expected rows from both result sets, got %v", sets)
+	}
+}
@@ -2,32 +2,151 @@ package errgo
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"runtime"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // MaxStackDepth is the maximum number of stackframes on any error.
 var MaxStackDepth = 50
 
+// MaxChainDepth is the maximum number of wrapped errors that Is will
+// follow before giving up. Chain reports deeper chains as cyclic.
+var MaxChainDepth = 100
+
+// MaxWrapDepth is the maximum number of prefixes and operations an error
+// message is built from, counting those of the StackableErrors it wraps,
+// whether through E, WrapPrefix or fmt.Errorf. Beyond it, which usually
+// comes from wrapping inside a loop by mistake, prefixes from the middle
+// of the message are dropped and only counted, so the outermost context
+// and the innermost cause are kept. Zero or less means no limit.
+var MaxWrapDepth = 100
+
+// MaxMessageLength caps the length in bytes of the message returned by
+// Error(). Longer messages are cut off and end with an ellipsis and the
+// length of the full message. Zero or less means no limit.
+var MaxMessageLength = 0
+
+// StackTraceHeader is written before the error message by StackTrace().
+var StackTraceHeader = "ERROR: "
+
+// StackTraceShowCode makes StackTrace() print the error's Code, if any,
+// below the message.
+var StackTraceShowCode = false
+
+// StackTraceShowTime makes StackTrace() print when the error was created
+// below the message.
+var StackTraceShowTime = false
+
+// StackTraceShowCauses makes StackTrace() append the message and stack of
+// every *StackableError wrapped inside the error.
+var StackTraceShowCauses = false
+
+// StackTraceShowFields makes StackTrace() print the error's fields, one
+// key=value pair per line, below the message.
+var StackTraceShowFields = false
+
+// StackReverse makes Stack() and StackTrace() list frames starting from
+// the outermost call, for readers used to root-first traces.
+var StackReverse = false
+
+// TraceEvents makes every new error log an event, tagged with its
+// fingerprint, to the runtime execution tracer (see runtime/trace), so
+// errors can be found on the trace timeline.
+var TraceEvents = false
+
 // StackableError is an error with an attached stacktrace. It can be used
 // wherever the builtin error interface is expected.
 type StackableError struct {
 	Err      error
 	Code     string
+	Op       Op
+	Kind     Kind
+	Severity Severity
 	Prefixes []string
 	stack    []uintptr
-	frames   []StackFrame
+	frames   *frameCache
+	frozen   bool
+	elided   int // prefixes dropped by WrapPrefix
+	elidedAt int // index in Prefixes the dropped prefixes were at
+	depth    int // prefixes and ops of the StackableErrors nested in Err
+	origin   *goOrigin
+	details  []interface{}
+	fields   []Field
+
+	breadcrumbs []Breadcrumb
+	annotations []frameNote
+	notes       []string
+	timestamp   time.Time
+	original    *StackableError // frozen error this one was copied from
+	scope       *scope
+	pooled      bool
+	processed   bool // has been through the processors installed with Use
+	fingerprint string
 }
 
 // Error returns the prefixed error message.
 func (err *StackableError) Error() string {
 	msg := err.Err.Error()
+	if len(err.Prefixes) == 0 && err.Op == "" {
+		return truncateMessage(msg)
+	}
+
+	note := ""
+	if err.elided > 0 {
+		note = fmt.Sprintf("(%d more prefixes elided): ", err.elided)
+	}
 
+	size := len(note) + len(msg)
 	for _, prefix := range err.Prefixes {
-		msg = fmt.Sprintf("%s: %s", prefix, msg)
+		size += len(prefix) + 2
+	}
+	if err.Op != "" {
+		size += len(err.Op) + 2
+	}
+
+	// Prefixes added later wrap the earlier ones, so write them in reverse.
+	var b strings.Builder
+	b.Grow(size)
+	for i := len(err.Prefixes) - 1; i >= 0; i-- {
+		if i == err.elidedAt-1 {
+			b.WriteString(note)
+		}
+		b.WriteString(err.Prefixes[i])
+		b.WriteString(": ")
+	}
+	if err.elidedAt == 0 {
+		b.WriteString(note)
+	}
+	if err.Op != "" {
+		b.WriteString(string(err.Op))
+		b.WriteString(": ")
+	}
+	b.WriteString(msg)
+
+	return truncateMessage(b.String())
+}
+
+// truncateMessage shortens msg to MaxMessageLength bytes without splitting
+// a multi-byte character.
+func truncateMessage(msg string) string {
+	if MaxMessageLength <= 0 || len(msg) <= MaxMessageLength {
+		return msg
+	}
+	cut := MaxMessageLength
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
 	}
+	return fmt.Sprintf("%s... (%d bytes total)", msg[:cut], len(msg))
+}
 
-	return msg
+// Unwrap returns the underlying error, for use with errors.Is and
+// errors.As.
+func (err *StackableError) Unwrap() error {
+	return err.Err
 }
 
 // Callers allows access to program counters.
@@ -37,90 +156,526 @@ func (err *StackableError) Callers() []uintptr {
 
 // Wrap makes a StackableError from an interface;
 // returns itself if the interface is already a *StackableError.
+// Errors holding a nil pointer are flagged as such in the message
+// (see IsTypedNil).
 func Wrap(e interface{}) *StackableError {
+	return wrap(e, 1)
+}
+
+// WrapSkip is like Wrap, but the stack leaves out the given number of
+// frames above the caller, for helpers that wrap errors on behalf of
+// their own callers.
+func WrapSkip(e interface{}, skip int) *StackableError {
+	return wrap(e, 1+skip)
+}
+
+// WrapPrefix makes a StackableError from the given value. If that value is already an
+// error then it will be used directly, if not, it will be passed to
+// fmt.Errorf("%v"). The prefix parameter is used to add a prefix to the
+// error message when calling Error().
+func WrapPrefix(e interface{}, prefix string) *StackableError {
+	return wrapPrefix(e, prefix, 1)
+}
+
+// WrapNil is like Wrap, but returns nil if err is nil, so its result can
+// be returned without checking err first.
+func WrapNil(err error) error {
+	if err == nil {
+		return nil
+	}
+	return wrap(err, 1)
+}
+
+// WrapPrefixNil is like WrapPrefix, but returns nil if err is nil.
+func WrapPrefixNil(err error, prefix string) error {
+	if err == nil {
+		return nil
+	}
+	return wrapPrefix(err, prefix, 1)
+}
+
+func wrap(e interface{}, skip int) *StackableError {
 	var err error
 
 	switch e := e.(type) {
 	case *StackableError:
+		if e == nil {
+			err = typedNilError{e}
+			break
+		}
 		return e // this adds a caller to the stack!
 	case error:
 		err = e
+		if IsTypedNil(e) {
+			err = typedNilError{e}
+		} else if converted := convert(e); converted != nil {
+			if converted.frozen {
+				converted = converted.clone()
+			}
+			converted.stack = captureStack(1 + skip)
+			converted.frames = newFrameCache(nil)
+			return created(converted)
+		}
 	default:
 		err = fmt.Errorf("%v", e)
 	}
 
-	return newStackableError(err, 1)
+	return created(newStackableError(err, 1+skip))
 }
 
-// WrapPrefix makes a StackableError from the given value. If that value is already an
-// error then it will be used directly, if not, it will be passed to
-// fmt.Errorf("%v"). The prefix parameter is used to add a prefix to the
-// error message when calling Error().
-func WrapPrefix(e interface{}, prefix string) *StackableError {
-	err := Wrap(e)
+func wrapPrefix(e interface{}, prefix string, skip int) *StackableError {
+	err := wrap(e, 1+skip)
+	if err.frozen {
+		err = err.clone()
+	}
+	if n := len(err.Prefixes); MaxWrapDepth > 0 && n > 0 && err.depth+n+err.ownOp() >= MaxWrapDepth {
+		// Drop a prefix from the middle, next to any dropped before, so
+		// the outermost and innermost ones are kept.
+		at := n / 2
+		if err.elided > 0 && err.elidedAt < n {
+			at = err.elidedAt
+		}
+		err.Prefixes = append(err.Prefixes[:at], err.Prefixes[at+1:]...)
+		err.elided++
+		err.elidedAt = at
+	}
 	err.Prefixes = append(err.Prefixes, prefix)
 	return err
 }
 
+// ownOp returns 1 if the error has an Op, so it counts towards
+// MaxWrapDepth like a prefix, and 0 otherwise.
+func (err *StackableError) ownOp() int {
+	if err.Op != "" {
+		return 1
+	}
+	return 0
+}
+
+// capDepth returns the cause for a new StackableError wrapping e, along
+// with the depth of that cause. If e nests StackableErrors as deep as
+// MaxWrapDepth, the prefixes and op of the outermost one are dropped from
+// the message of the cause, and counted in it instead.
+func capDepth(e error) (error, int) {
+	if MaxWrapDepth <= 0 {
+		return e, 0
+	}
+	var inner *StackableError
+	for err, i := e, 0; err != nil && i < MaxChainDepth; err, i = unwrapOnce(err), i+1 {
+		if se, ok := err.(*StackableError); ok {
+			inner = se
+			break
+		}
+	}
+	if inner == nil {
+		return e, 0
+	}
+	own := len(inner.Prefixes) + inner.ownOp()
+	if inner.depth+own < MaxWrapDepth {
+		return e, inner.depth + own
+	}
+
+	// Only the text of the wrappers between e and inner is kept, so it
+	// must come before the message of inner, as with fmt.Errorf("...: %w").
+	msg, innerMsg := e.Error(), inner.Error()
+	if !strings.HasSuffix(msg, innerMsg) {
+		return e, inner.depth + own
+	}
+	elided := &elidedError{
+		prefix: msg[:len(msg)-len(innerMsg)],
+		n:      own + inner.elided,
+		err:    inner.Err,
+	}
+	if nested, ok := inner.Err.(*elidedError); ok {
+		elided.n += nested.n
+		if nested.prefix != "" {
+			elided.n++
+		}
+		elided.err = nested.err
+	}
+	return elided, inner.depth
+}
+
+// elidedError stands in for a chain of wrapped errors whose outermost
+// StackableError was dropped from the message by capDepth.
+type elidedError struct {
+	prefix string
+	n      int
+	err    error
+}
+
+func (e *elidedError) Error() string {
+	return fmt.Sprintf("%s(%d more prefixes elided): %s", e.prefix, e.n, e.err.Error())
+}
+
+func (e *elidedError) Unwrap() error { return e.err }
+
+// Freeze marks the error as immutable and returns it. Functions that
+// would otherwise modify a frozen error, such as WrapPrefix, return a
+// modified copy instead, which makes package-level sentinel errors safe
+// to wrap.
+func (err *StackableError) Freeze() *StackableError {
+	err.frozen = true
+	return err
+}
+
+// Frozen reports whether the error has been marked immutable by Freeze.
+func (err *StackableError) Frozen() bool {
+	return err.frozen
+}
+
+// clone returns an unfrozen copy of the error that does not share its
+// prefixes, details or fields with the original. A copy of a frozen error
+// remembers it, so the copy still matches it in errors.Is.
+func (err *StackableError) clone() *StackableError {
+	cp := *err
+	if err.frozen {
+		cp.original = err
+	}
+	cp.Prefixes = append([]string(nil), err.Prefixes...)
+	cp.details = append([]interface{}(nil), err.details...)
+	cp.fields = append([]Field(nil), err.fields...)
+	cp.annotations = append([]frameNote(nil), err.annotations...)
+	cp.notes = append([]string(nil), err.notes...)
+	cp.frozen = false
+	cp.pooled = false
+	return &cp
+}
+
 func newStackableError(e error, skip int) *StackableError {
 	var prefixes []string
-	stack := make([]uintptr, MaxStackDepth)
-	length := runtime.Callers(2+skip, stack)
+	e, depth := capDepth(e)
 	return &StackableError{
 		Err:      e,
-		stack:    stack[:length],
+		stack:    captureStack(1 + skip),
 		Prefixes: prefixes,
+		frames:   newFrameCache(nil),
+		depth:    depth,
+	}
+}
+
+// created finishes a new error once its constructor has filled it in: it
+// stamps it with the current time, takes the diagnostics requested by
+// DiagnoseKinds, runs the processors installed with Use, logs a trace
+// event if TraceEvents is set and sends the error to subscribers.
+func created(err *StackableError) *StackableError {
+	if err.timestamp.IsZero() {
+		err.timestamp = now()
+	}
+	diagnoseKind(err)
+	err = process(err)
+	if TraceEvents {
+		traceEvent(err)
+	}
+	publish(err)
+	return err
+}
+
+// WrapFrames makes a StackableError from the given value like Wrap, but
+// uses frames that were already resolved elsewhere, e.g. decoded from a
+// serialized error, instead of capturing the current stack. A
+// *StackableError is copied with everything but its stack kept.
+func WrapFrames(e interface{}, frames []StackFrame) *StackableError {
+	stack := framesStack(frames)
+
+	var err error
+
+	switch e := e.(type) {
+	case *StackableError:
+		if e == nil {
+			err = typedNilError{e}
+			break
+		}
+		cp := e.clone()
+		cp.stack = stack
+		cp.frames = newFrameCache(frames)
+		return created(cp)
+	case error:
+		err = e
+	default:
+		err = fmt.Errorf("%v", e)
+	}
+
+	return created(&StackableError{
+		Err:    err,
+		stack:  stack,
+		frames: newFrameCache(frames),
+	})
+}
+
+// framesStack returns the program counters of frames, or nil if any
+// frame lacks one.
+func framesStack(frames []StackFrame) []uintptr {
+	var stack []uintptr
+	for _, frame := range frames {
+		if frame.Caller == 0 {
+			return nil
+		}
+		stack = append(stack, frame.Caller)
+	}
+	return stack
+}
+
+// DetachStack returns a copy of err without its stack, for errors that are
+// kept around long after they were logged. The original error keeps its
+// trace, and the copy keeps its fingerprint. Every *StackableError wrapped
+// inside err, such as the causes nested by E, is detached too; a wrapper
+// around one of them, such as fmt.Errorf with %w, is replaced by one with
+// the same message. Errors that are not a *StackableError are returned
+// unchanged.
+func DetachStack(err error) error {
+	e, ok := err.(*StackableError)
+	if !ok {
+		return err
+	}
+	return detach(e, 0)
+}
+
+// detach returns a detached copy of e, found depth errors into the chain
+// being detached, so a chain that refers back to itself ends at
+// MaxChainDepth.
+func detach(e *StackableError, depth int) *StackableError {
+	detached := e.clone()
+	detached.fingerprint = e.Fingerprint()
+	detached.stack = nil
+	detached.frames = newFrameCache([]StackFrame{})
+	detached.Err, _ = detachCause(e.Err, depth+1)
+	return detached
+}
+
+// detachCause returns err with the *StackableError it wraps, if any,
+// detached, and whether it found one.
+func detachCause(err error, depth int) (error, bool) {
+	if err == nil || depth >= MaxChainDepth {
+		return err, false
+	}
+	if e, ok := err.(*StackableError); ok {
+		return detach(e, depth), true
 	}
+	u, ok := err.(interface{ Unwrap() error })
+	if !ok {
+		return err, false
+	}
+	inner, found := detachCause(u.Unwrap(), depth+1)
+	if !found {
+		return err, false
+	}
+	return &detachedWrapper{msg: err.Error(), err: inner}, true
 }
 
+// detachedWrapper stands in for a wrapper around a detached error.
+type detachedWrapper struct {
+	msg string
+	err error
+}
+
+func (w *detachedWrapper) Error() string { return w.msg }
+func (w *detachedWrapper) Unwrap() error { return w.err }
+
 // Is detects whether the error is equal to a given error. Errors
 // are considered equal by this function if they are the same object,
 // or if they both contain the same error inside an errors.Error.
+// Wrapped errors are followed up to MaxChainDepth levels, including each
+// error joined with errors.Join or held by a multi-error, and an error on
+// either side with an Is(error) bool method is asked for a match.
 func Is(e error, original error) bool {
-	if e == original {
-		return true
+	if e == nil || original == nil {
+		return e == original
 	}
+	return is(e, original, 0)
+}
 
-	if e, ok := e.(*StackableError); ok {
-		return Is(e.Err, original)
+func is(e, original error, depth int) bool {
+	for err := e; err != nil && depth < MaxChainDepth; err, depth = unwrapOnce(err), depth+1 {
+		j := 0
+		for target := original; target != nil && j < MaxChainDepth; target, j = unwrapOnce(target), j+1 {
+			if isMatch(err, target) {
+				return true
+			}
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				if err != nil && is(err, original, depth+1) {
+					return true
+				}
+			}
+		}
 	}
 
-	if original, ok := original.(*StackableError); ok {
-		return Is(e, original.Err)
+	return false
+}
+
+// isMatch reports whether err and target are equal, or one of them says
+// it matches the other. Like errors.Is, it only compares errors with ==
+// when the type of target is comparable, since comparing values of an
+// uncomparable type such as a slice panics.
+func isMatch(err, target error) bool {
+	if reflect.TypeOf(target).Comparable() && err == target {
+		return true
 	}
+	if err, ok := err.(interface{ Is(error) bool }); ok && err.Is(target) {
+		return true
+	}
+	if target, ok := target.(interface{ Is(error) bool }); ok && target.Is(err) {
+		return true
+	}
+	return false
+}
 
+// Is reports whether target is a frozen error that err was copied from,
+// e.g. by WrapPrefix or WithField, so errors.Is(WrapPrefix(sentinel, "x"),
+// sentinel) holds for a frozen sentinel.
+func (err *StackableError) Is(target error) bool {
+	t, ok := target.(*StackableError)
+	if !ok || t == nil {
+		return false
+	}
+	for o := err.original; o != nil; o = o.original {
+		if o == t {
+			return true
+		}
+	}
 	return false
 }
 
+// walkChain returns err followed by every error it wraps, stopping after
+// MaxChainDepth errors or when the same pointer error is seen twice. The
+// returned bool is false if the walk was cut short.
+func walkChain(err error) ([]error, bool) {
+	var errs []error
+	seen := map[error]bool{}
+
+	for ; err != nil; err = unwrapOnce(err) {
+		if len(errs) >= MaxChainDepth {
+			return errs, false
+		}
+		if reflect.ValueOf(err).Kind() == reflect.Ptr {
+			if seen[err] {
+				return errs, false
+			}
+			seen[err] = true
+		}
+		errs = append(errs, err)
+	}
+
+	return errs, true
+}
+
+// Chain returns err followed by every error it wraps. The returned bool
+// is true if the chain refers back to itself, or is deeper than
+// MaxChainDepth, in which case the errors up to that point are returned.
+func Chain(err error) ([]error, bool) {
+	errs, ok := walkChain(err)
+	return errs, !ok
+}
+
 // StackFrames returns an array of frames containing information about the
-// stack.
+// stack. The frames are resolved once and shared with any copies of the
+// error.
 func (err *StackableError) StackFrames() []StackFrame {
 	if err.frames == nil {
-		err.frames = make([]StackFrame, len(err.stack))
-		for i, pc := range err.stack {
-			err.frames[i] = NewStackFrame(pc)
-		}
+		return resolveFrames(err.stack)
 	}
-
-	return err.frames
+	return err.frames.get(err.stack)
 }
 
 // Stack returns the callstack formatted the same way that go does
-// in runtime/debug.Stack()
+// in runtime/debug.Stack(), or outermost call first if StackReverse is set.
+// Notes added with AnnotateFrame are printed below their frames.
 func (err *StackableError) Stack() string {
 	buf := bytes.Buffer{}
-
-	for _, frame := range err.StackFrames() {
-		buf.WriteString(frame.String())
-		buf.WriteString("\n")
-	}
-
+	writeAnnotatedFrames(&buf, err.StackFrames(), err.annotations)
 	return buf.String()
 }
 
 // StackTrace prints a stacktrace like:
 // ERROR: (prefixed message)
 // (stack returned by Stack())
+//
+// The layout can be adjusted with StackTraceHeader, StackTraceShowCode,
+// StackTraceShowTime, StackTraceShowFields and StackTraceShowCauses.
 func (err *StackableError) StackTrace() string {
-	return "ERROR: " + err.Error() + "\n" + err.Stack()
+	buf := bytes.Buffer{}
+	err.writeTraceHeader(&buf)
+	buf.WriteString(err.Stack())
+	err.writeTraceFooter(&buf)
+	return buf.String()
+}
+
+// writeTraceHeader writes the part of StackTrace above the stack.
+func (err *StackableError) writeTraceHeader(buf *bytes.Buffer) {
+	buf.WriteString(StackTraceHeader + err.Error() + "\n")
+	if StackTraceShowCode && err.Code != "" {
+		buf.WriteString("CODE: " + err.Code + "\n")
+	}
+	if StackTraceShowTime && !err.timestamp.IsZero() {
+		buf.WriteString("TIME: " + err.timestamp.Format(time.RFC3339Nano) + "\n")
+	}
+	if StackTraceShowFields && len(err.fields) > 0 {
+		buf.WriteString("FIELDS:\n" + err.fieldLines())
+	}
+}
+
+// writeTraceFooter writes the part of StackTrace below the stack.
+func (err *StackableError) writeTraceFooter(buf *bytes.Buffer) {
+	buf.WriteString(err.originStack())
+	if len(err.breadcrumbs) > 0 {
+		buf.WriteString("BREADCRUMBS:\n" + err.breadcrumbLines())
+	}
+	if len(err.notes) > 0 {
+		buf.WriteString("NOTES:\n" + strings.Join(err.notes, "\n") + "\n")
+	}
+
+	if StackTraceShowCauses {
+		causes, _ := walkChain(err)
+		for _, cause := range causes[1:] {
+			if cause, ok := cause.(*StackableError); ok {
+				buf.WriteString("CAUSED BY: " + cause.Error() + "\n")
+				buf.WriteString(cause.Stack())
+			}
+		}
+	}
+}
+
+// Format implements fmt.Formatter. %s and %v print the message, %q
+// quotes it and %x/%X hex-encode it, as they would for any other error.
+// %+v prints the full StackTrace().
+func (err *StackableError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprint(s, err.StackTrace())
+			return
+		}
+		if s.Flag('#') {
+			fmt.Fprint(s, err.GoString())
+			return
+		}
+		fmt.Fprintf(s, fmt.FormatString(s, 's'), err.Error())
+	case 's', 'q', 'x', 'X':
+		fmt.Fprintf(s, fmt.FormatString(s, verb), err.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errgo.StackableError=%s)", verb, err.Error())
+	}
+}
+
+// GoString implements fmt.GoStringer, which is used by %#v. It shows the
+// type, message, code and top stack frame of the error.
+func (err *StackableError) GoString() string {
+	top := ""
+	if frames := err.StackFrames(); len(frames) > 0 {
+		top = frames[0].String()
+	}
+	return fmt.Sprintf("&errgo.StackableError{Message:%q, Code:%q, Frame:%q}", err.Error(), err.Code, top)
+}
+
+// unwrapOnce returns the error wrapped by err, if any.
+func unwrapOnce(err error) error {
+	if e, ok := err.(*StackableError); ok {
+		if e == nil {
+			return nil
+		}
+		return e.Err
+	}
+	return errors.Unwrap(err)
 }
@@ -304,3 +304,444 @@ func callersToFrames(callers []uintptr) []runtime.Frame {
 	}
 }
 */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requireStacks skips tests that inspect stacks when built with the
+// errgo_nostack tag.
+func requireStacks(t *testing.T) {
+	t.Helper()
+	if len(captureStack(0)) == 0 {
+		t.Skip("stacks are not captured in this build")
+	}
+}
+
+func TestFormatVerbs(t *testing.T) {
+	err := WrapPrefix("hi", "prefix")
+
+	cases := map[string]string{
+		"%s":   "prefix: hi",
+		"%v":   "prefix: hi",
+		"%q":   `"prefix: hi"`,
+		"%x":   "7072656669783a206869",
+		"%X":   "7072656669783A206869",
+		"% x":  "70 72 65 66 69 78 3a 20 68 69",
+		"%12s": "  prefix: hi",
+		"%d":   "%!d(*errgo.StackableError=prefix: hi)",
+	}
+
+	for format, expected := range cases {
+		if actual := fmt.Sprintf(format, err); actual != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, actual)
+		}
+	}
+
+	if actual := fmt.Sprintf("%+v", err); actual != err.StackTrace() {
+		t.Errorf("%%+v: expected the stack trace, got %q", actual)
+	}
+
+	if actual := fmt.Sprintf("%#v", err); !strings.HasPrefix(actual, `&errgo.StackableError{Message:"prefix: hi", Code:""`) {
+		t.Errorf("%%#v: expected a Go representation, got %q", actual)
+	}
+}
+
+func TestFrozenSentinelIs(t *testing.T) {
+	sentinel := Wrap("not found").Freeze()
+
+	copies := map[string]error{
+		"WrapPrefix":        WrapPrefix(sentinel, "x"),
+		"WithField":         sentinel.WithField("id", 1),
+		"AddDetail":         sentinel.AddDetail("detail"),
+		"nested WrapPrefix": WrapPrefix(WrapPrefix(sentinel, "x").Freeze(), "y"),
+		"fmt.Errorf":        fmt.Errorf("outer: %w", WrapPrefix(sentinel, "x")),
+	}
+	for name, err := range copies {
+		if err == error(sentinel) {
+			t.Errorf("%s: expected a copy of the frozen sentinel", name)
+		}
+		if !errors.Is(err, sentinel) {
+			t.Errorf("%s: errors.Is did not match the sentinel", name)
+		}
+		if !Is(err, sentinel) {
+			t.Errorf("%s: Is did not match the sentinel", name)
+		}
+	}
+
+	if errors.Is(Wrap("not found"), sentinel) {
+		t.Error("an unrelated error matched the sentinel")
+	}
+}
+
+type sliceError []string
+
+func (e sliceError) Error() string { return strings.Join(e, ", ") }
+
+type loopError struct{}
+
+func (e *loopError) Error() string { return "loop" }
+func (e *loopError) Unwrap() error { return e }
+
+type matchAll struct{}
+
+func (matchAll) Error() string        { return "match all" }
+func (matchAll) Is(target error) bool { return true }
+
+func TestIsChains(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	wrapped := fmt.Errorf("outer: %w", WrapPrefix(sentinel, "inner"))
+
+	cases := []struct {
+		name     string
+		err      error
+		original error
+		expected bool
+	}{
+		{"same error", sentinel, sentinel, true},
+		{"wrapped", wrapped, sentinel, true},
+		{"both wrapped", wrapped, Wrap(sentinel), true},
+		{"unrelated", wrapped, errors.New("sentinel"), false},
+		{"nil", nil, nil, true},
+		{"nil and error", nil, sentinel, false},
+		{"uncomparable on both sides", Wrap(sliceError{"a"}), sliceError{"a"}, false},
+		{"uncomparable target", wrapped, sliceError{"a"}, false},
+		{"Is method on err", matchAll{}, sentinel, true},
+		{"Is method on original", sentinel, Wrap(matchAll{}), true},
+		{"cycle", &loopError{}, sentinel, false},
+		{"joined", fmt.Errorf("batch: %w", errors.Join(errors.New("a"), wrapped)), sentinel, true},
+		{"multi-error", Wrap(Append(errors.New("a"), wrapped)), sentinel, true},
+		{"joined unrelated", errors.Join(errors.New("a"), errors.New("b")), sentinel, false},
+	}
+
+	for _, c := range cases {
+		if actual := Is(c.err, c.original); actual != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestDetachStack(t *testing.T) {
+	requireStacks(t)
+	base := errors.New("missing")
+	inner := E("db.Get", NotExist, base)
+
+	for name, err := range map[string]*StackableError{
+		"nested":     E("svc.Load", Other, inner),
+		"with args":  E("svc.Load", Other, inner, "user 1"),
+		"fmt.Errorf": Wrap(fmt.Errorf("loading: %w", inner)),
+	} {
+		detached := DetachStack(err)
+		if detached.Error() != err.Error() {
+			t.Errorf("%s: expected message %q, got %q", name, err.Error(), detached.Error())
+		}
+		if fp := detached.(*StackableError).Fingerprint(); fp != err.Fingerprint() {
+			t.Errorf("%s: expected fingerprint %s, got %s", name, err.Fingerprint(), fp)
+		}
+		if !errors.Is(detached, base) {
+			t.Errorf("%s: detached error no longer matches its cause", name)
+		}
+		if KindOf(detached) != NotExist {
+			t.Errorf("%s: expected kind NotExist, got %v", name, KindOf(detached))
+		}
+
+		var n int
+		for e := error(detached); e != nil; e = errors.Unwrap(e) {
+			if se, ok := e.(*StackableError); ok {
+				n++
+				if len(se.Callers()) != 0 || len(se.StackFrames()) != 0 {
+					t.Errorf("%s: a wrapped error kept its stack", name)
+				}
+			}
+		}
+		if n != 2 {
+			t.Errorf("%s: expected 2 detached errors in the chain, got %d", name, n)
+		}
+		if len(err.StackFrames()) == 0 || len(inner.StackFrames()) == 0 {
+			t.Errorf("%s: the original error lost its stack", name)
+		}
+	}
+}
+
+func TestWrapFramesKeepsError(t *testing.T) {
+	requireStacks(t)
+	frames := []StackFrame{{File: "remote.go", LineNumber: 7, FunctionName: "Handle", Package: "remote"}}
+	orig := E("svc.Load", NotExist, errors.New("missing"))
+	orig.Code = "E42"
+	orig.Severity = SeverityWarning
+	orig = WrapPrefix(orig, "prefix").WithField("id", 1)
+
+	err := WrapFrames(orig, frames)
+	if err == orig {
+		t.Fatal("expected a copy")
+	}
+	if err.Error() != orig.Error() || err.Code != "E42" || err.Kind != NotExist || err.Severity != SeverityWarning || err.Op != "svc.Load" {
+		t.Errorf("WrapFrames lost part of the error: %#v", err)
+	}
+	if v, ok := err.Field("id"); !ok || v != 1 {
+		t.Errorf("expected field id=1, got %v", v)
+	}
+	if actual := err.StackFrames(); len(actual) != 1 || actual[0].File != "remote.go" {
+		t.Errorf("expected the given frames, got %v", actual)
+	}
+	if actual := orig.StackFrames(); len(actual) == 0 || actual[0].File == "remote.go" {
+		t.Error("WrapFrames changed the frames of the original error")
+	}
+}
+
+func TestEntryFramesElided(t *testing.T) {
+	requireStacks(t)
+	defer func(elide bool) { ElideEntryFrames = elide }(ElideEntryFrames)
+
+	for _, elide := range []bool{true, false} {
+		ElideEntryFrames = elide
+		found := false
+		for _, frame := range Wrap("x").StackFrames() {
+			if frame.Package == "testing" && frame.FunctionName == "tRunner" {
+				found = true
+			}
+			if strings.HasPrefix(frame.Package, "internal/") {
+				t.Errorf("frame attributed to inlined code: %v", frame)
+			}
+		}
+		if found == elide {
+			t.Errorf("ElideEntryFrames=%v: testing.tRunner in stack: %v", elide, found)
+		}
+	}
+}
+
+func TestErrorAllocations(t *testing.T) {
+	withOp := E("svc.Get", NotExist, errors.New("missing"))
+	cases := map[string]*StackableError{
+		"prefixes":        WrapPrefix(WrapPrefix(WrapPrefix("hi", "a"), "b"), "c"),
+		"op":              withOp,
+		"op and prefixes": WrapPrefix(withOp, "handler"),
+	}
+	for name, err := range cases {
+		if allocs := testing.AllocsPerRun(100, func() { _ = err.Error() }); allocs > 1 {
+			t.Errorf("%s: expected Error() to allocate once, got %v allocations", name, allocs)
+		}
+	}
+	if actual := cases["op and prefixes"].Error(); actual != "handler: svc.Get: missing" {
+		t.Errorf("unexpected message %q", actual)
+	}
+}
+
+func BenchmarkError(b *testing.B) {
+	for _, n := range []int{0, 1, 5, 20} {
+		err := E("net.Dial", IO, errors.New("connection refused"))
+		for i := 0; i < n; i++ {
+			err = WrapPrefix(err, fmt.Sprintf("layer %d", i))
+		}
+		b.Run(fmt.Sprintf("%d prefixes", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = err.Error()
+			}
+		})
+	}
+}
+
+func TestMaxWrapDepth(t *testing.T) {
+	defer func(depth int) { MaxWrapDepth = depth }(MaxWrapDepth)
+	MaxWrapDepth = 4
+	base := errors.New("msg")
+
+	cases := []struct {
+		name     string
+		wrap     func(err error, i int) error
+		expected string
+	}{
+		{
+			"WrapPrefix",
+			func(err error, i int) error { return WrapPrefix(err, fmt.Sprint("p", i)) },
+			"p10: p9: (6 more prefixes elided): p2: p1: msg",
+		},
+		{
+			"E",
+			func(err error, i int) error { return E(Op(fmt.Sprint("op", i)), Other, err) },
+			"op10: (6 more prefixes elided): op3: op2: op1: msg",
+		},
+		{
+			"fmt.Errorf",
+			func(err error, i int) error { return WrapPrefix(fmt.Errorf("retry %d: %w", i, err), "attempt") },
+			"attempt: retry 10: (11 more prefixes elided): retry 4: attempt: retry 3: attempt: retry 2: attempt: retry 1: msg",
+		},
+	}
+	for _, c := range cases {
+		var err error = base
+		for i := 1; i <= 10; i++ {
+			err = c.wrap(err, i)
+		}
+		if err.Error() != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, err.Error())
+		}
+		if !errors.Is(err, base) {
+			t.Errorf("%s: expected the error to still wrap its cause", c.name)
+		}
+	}
+}
+
+// backError wraps the StackableError holding it, making a cycle.
+type backError struct{ e *StackableError }
+
+func (b *backError) Error() string { return "back" }
+func (b *backError) Unwrap() error { return b.e }
+
+func TestDetachStackCycle(t *testing.T) {
+	e := &StackableError{frames: newFrameCache(nil)}
+	e.Err = &backError{e}
+
+	detached := DetachStack(e)
+	if detached.Error() != "back" {
+		t.Errorf("expected message %q, got %q", "back", detached.Error())
+	}
+}
+
+func TestMaxMessageLength(t *testing.T) {
+	defer func(n int) { MaxMessageLength = n }(MaxMessageLength)
+
+	cases := []struct {
+		name     string
+		limit    int
+		err      error
+		expected string
+	}{
+		{"no limit", 0, Wrap(errors.New("a long message")), "a long message"},
+		{"short", 20, Wrap(errors.New("short")), "short"},
+		{"exact", 5, Wrap(errors.New("exact")), "exact"},
+		{"long", 6, Wrap(errors.New("a long message")), "a long... (14 bytes total)"},
+		{"prefixes", 8, WrapPrefix(errors.New("message"), "outer"), "outer: m... (14 bytes total)"},
+		{"multi-byte", 3, Wrap(errors.New("héllo")), "hé... (6 bytes total)"},
+		{"inside a character", 2, Wrap(errors.New("héllo")), "h... (6 bytes total)"},
+	}
+	for _, c := range cases {
+		MaxMessageLength = c.limit
+		if actual := c.err.Error(); actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestStackTraceLayout(t *testing.T) {
+	defer func(header string, code, showTime, fields, causes bool) {
+		StackTraceHeader = header
+		StackTraceShowCode, StackTraceShowTime, StackTraceShowFields, StackTraceShowCauses = code, showTime, fields, causes
+	}(StackTraceHeader, StackTraceShowCode, StackTraceShowTime, StackTraceShowFields, StackTraceShowCauses)
+	SetNow(func() time.Time { return time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC) })
+	defer SetNow(nil)
+
+	inner := E("db.Get", NotExist, nil)
+	err := E("svc.Load", Other, inner).WithField("id", 7)
+	err.Code = "user_missing"
+
+	cases := []struct {
+		name       string
+		set        func()
+		contains   []string
+		notContain []string
+	}{
+		{
+			"default",
+			func() {},
+			[]string{"ERROR: svc.Load: db.Get: item does not exist\n"},
+			[]string{"CODE:", "TIME:", "FIELDS:", "CAUSED BY:"},
+		},
+		{"header", func() { StackTraceHeader = "E " }, []string{"E svc.Load"}, []string{"ERROR: "}},
+		{"code", func() { StackTraceShowCode = true }, []string{"CODE: user_missing\n"}, nil},
+		{"time", func() { StackTraceShowTime = true }, []string{"TIME: 2024-05-06T07:08:09Z\n"}, nil},
+		{"fields", func() { StackTraceShowFields = true }, []string{"FIELDS:\nid=7\n"}, nil},
+		{"causes", func() { StackTraceShowCauses = true }, []string{"CAUSED BY: db.Get: item does not exist\n"}, nil},
+	}
+	for _, c := range cases {
+		StackTraceHeader = "ERROR: "
+		StackTraceShowCode, StackTraceShowTime, StackTraceShowFields, StackTraceShowCauses = false, false, false, false
+		c.set()
+
+		trace := err.StackTrace()
+		for _, s := range c.contains {
+			if !strings.Contains(trace, s) {
+				t.Errorf("%s: expected %q in:\n%s", c.name, s, trace)
+			}
+		}
+		for _, s := range c.notContain {
+			if strings.Contains(trace, s) {
+				t.Errorf("%s: expected no %q in:\n%s", c.name, s, trace)
+			}
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	defer func(depth int) { MaxChainDepth = depth }(MaxChainDepth)
+	MaxChainDepth = 10
+
+	base := errors.New("base")
+	var deep error = base
+	for i := 0; i < 20; i++ {
+		deep = fmt.Errorf("%d: %w", i, deep)
+	}
+	back := &StackableError{frames: newFrameCache(nil)}
+	back.Err = &backError{back}
+
+	cases := []struct {
+		name   string
+		err    error
+		length int
+		cyclic bool
+	}{
+		{"nil", nil, 0, false},
+		{"single", base, 1, false},
+		{"wrapped", fmt.Errorf("outer: %w", WrapPrefix(base, "inner")), 3, false},
+		{"self", &loopError{}, 1, true},
+		{"through a StackableError", back, 2, true},
+		{"too deep", deep, 10, true},
+	}
+	for _, c := range cases {
+		errs, cyclic := Chain(c.err)
+		if len(errs) != c.length || cyclic != c.cyclic {
+			t.Errorf("%s: expected %d errors and cyclic %v, got %d and %v", c.name, c.length, c.cyclic, len(errs), cyclic)
+		}
+	}
+}
+
+func TestWrapNil(t *testing.T) {
+	base := errors.New("boom")
+	cases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"WrapNil nil", WrapNil(nil), ""},
+		{"WrapNil", WrapNil(base), "boom"},
+		{"WrapPrefixNil nil", WrapPrefixNil(nil, "load"), ""},
+		{"WrapPrefixNil", WrapPrefixNil(base, "load"), "load: boom"},
+	}
+	for _, c := range cases {
+		if c.expected == "" {
+			if c.err != nil {
+				t.Errorf("%s: expected an untyped nil, got %#v", c.name, c.err)
+			}
+			continue
+		}
+		if _, ok := c.err.(*StackableError); !ok || c.err.Error() != c.expected {
+			t.Errorf("%s: expected a StackableError %q, got %#v", c.name, c.expected, c.err)
+		}
+	}
+}
+
+func TestWrapNilStack(t *testing.T) {
+	requireStacks(t)
+	for name, err := range map[string]error{
+		"WrapNil":       WrapNil(errors.New("x")),
+		"WrapPrefixNil": WrapPrefixNil(errors.New("x"), "p"),
+	} {
+		if fn := err.(*StackableError).StackFrames()[0].FunctionName; fn != "TestWrapNilStack" {
+			t.Errorf("%s: expected the stack to start at the caller, got %s", name, fn)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package errtest provides helpers for testing code that uses errgo.
+package errtest
+
+import (
+	"flag"
+	"go/build"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+// Wrap wraps err like errgo.Wrap, with the stack starting at the caller
+// of Wrap, and records the name of the test in the "test" field, for
+// errors that travel through channels or goroutines before being checked.
+// If the test fails, the full stack trace of the error is logged when the
+// test finishes. A nil err gives nil.
+func Wrap(tb testing.TB, err error) error {
+	tb.Helper()
+	if err == nil {
+		return nil
+	}
+
+	e := errgo.WrapSkip(err, 1).WithField("test", tb.Name())
+	tb.Cleanup(func() {
+		if tb.Failed() {
+			tb.Logf("error wrapped by errtest.Wrap:\n%s", e.StackTrace())
+		}
+	})
+	return e
+}
+
+// UpdateEnv is the environment variable that makes Golden write golden
+// files instead of comparing against them.
+const UpdateEnv = "ERRGO_UPDATE_GOLDEN"
+
+// updating reports whether golden files should be written: UpdateEnv is
+// set to a true value, or the test package defines its own -update flag
+// and it was passed.
+func updating() bool {
+	if update, err := strconv.ParseBool(os.Getenv(UpdateEnv)); err == nil && update {
+		return true
+	}
+	f := flag.Lookup("update")
+	return f != nil && f.Value.String() == "true"
+}
+
+// Golden compares the stack trace of err against the golden file at path,
+// after normalizing line numbers, GOROOT paths and memory addresses so
+// the comparison does not break on unrelated edits. Running the test with
+// ERRGO_UPDATE_GOLDEN=1, or with -update if the test package defines that
+// flag, writes the current trace to the file instead.
+//
+// Errors that are not a *errgo.StackableError are compared by message.
+// The options are passed on to NormalizeStack.
+func Golden(t testing.TB, err error, path string, opts ...NormalizeOption) {
+	t.Helper()
+
+	got := NormalizeStack(render(err), opts...)
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, readErr := os.ReadFile(path)
+	if readErr != nil {
+		t.Fatalf("%v (run with %s=1 to create it)", readErr, UpdateEnv)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match (run with %s=1 to accept)\n--- want:\n%s\n--- got:\n%s", path, UpdateEnv, want, got)
+	}
+}
+
+func render(err error) string {
+	if err == nil {
+		return "<nil>\n"
+	}
+	if e, ok := err.(*errgo.StackableError); ok {
+		return e.StackTrace()
+	}
+	return err.Error() + "\n"
+}
+
+var (
+	lineNumbers = regexp.MustCompile(`(line |\.go:|\.s:)\d+`)
+	addresses   = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+)
+
+type normalizer struct {
+	keepLines     bool
+	keepAddresses bool
+	paths         []string
+}
+
+// A NormalizeOption changes what NormalizeStack replaces.
+type NormalizeOption func(*normalizer)
+
+// KeepLineNumbers leaves line numbers as they are.
+func KeepLineNumbers() NormalizeOption {
+	return func(n *normalizer) { n.keepLines = true }
+}
+
+// KeepAddresses leaves hexadecimal addresses and offsets as they are.
+func KeepAddresses() NormalizeOption {
+	return func(n *normalizer) { n.keepAddresses = true }
+}
+
+// ReplacePath replaces every occurrence of the path prefix old with new,
+// e.g. to hide the checkout directory of the module under test.
+func ReplacePath(old, new string) NormalizeOption {
+	return func(n *normalizer) { n.paths = append(n.paths, old, new) }
+}
+
+// NormalizeStack rewrites a stack trace so it can be compared across
+// machines and edits: the GOROOT directory becomes $GOROOT, line numbers
+// become N and hexadecimal addresses become 0xADDR. This is what Golden
+// does before comparing.
+func NormalizeStack(s string, opts ...NormalizeOption) string {
+	n := normalizer{}
+	if goroot := build.Default.GOROOT; goroot != "" {
+		n.paths = append(n.paths, goroot, "$GOROOT")
+	}
+	for _, opt := range opts {
+		opt(&n)
+	}
+
+	s = strings.NewReplacer(n.paths...).Replace(s)
+	if !n.keepLines {
+		s = lineNumbers.ReplaceAllString(s, "${1}N")
+	}
+	if !n.keepAddresses {
+		s = addresses.ReplaceAllString(s, "0xADDR")
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package errtest
+
+import (
+	"errors"
+	"flag"
+	"go/build"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/freemish/errgo"
+)
+
+// Test packages commonly define their own -update flag; importing errtest
+// must not conflict with it.
+var _ = flag.Bool("update", false, "update golden files")
+
+func TestGoldenUpdateEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.txt")
+	err := errors.New("boom")
+
+	t.Setenv(UpdateEnv, "1")
+	Golden(t, err, path)
+	if _, statErr := os.Stat(path); statErr != nil {
+		t.Fatalf("golden file was not written: %v", statErr)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	Golden(t, err, path)
+}
+
+func TestWrapStartsAtCaller(t *testing.T) {
+	err := Wrap(t, errors.New("boom")).(*errgo.StackableError)
+
+	if frames := err.StackFrames(); len(err.Callers()) > 0 && frames[0].FunctionName != "TestWrapStartsAtCaller" {
+		t.Errorf("expected the stack to start at the test, got %v", frames)
+	}
+	if name, _ := err.Field("test"); name != t.Name() {
+		t.Errorf("expected the test field to be %q, got %v", t.Name(), name)
+	}
+}
+
+func TestNormalizeStack(t *testing.T) {
+	goroot := build.Default.GOROOT
+	trace := "main.go:42 main.run\n\t" + goroot + "/src/runtime/proc.go:250 +0x1d3\n/home/me/app/x.go:7"
+
+	cases := []struct {
+		name     string
+		opts     []NormalizeOption
+		expected string
+	}{
+		{
+			"defaults",
+			nil,
+			"main.go:N main.run\n\t$GOROOT/src/runtime/proc.go:N +0xADDR\n/home/me/app/x.go:N",
+		},
+		{
+			"keep line numbers",
+			[]NormalizeOption{KeepLineNumbers()},
+			"main.go:42 main.run\n\t$GOROOT/src/runtime/proc.go:250 +0xADDR\n/home/me/app/x.go:7",
+		},
+		{
+			"keep addresses",
+			[]NormalizeOption{KeepAddresses()},
+			"main.go:N main.run\n\t$GOROOT/src/runtime/proc.go:N +0x1d3\n/home/me/app/x.go:N",
+		},
+		{
+			"replace path",
+			[]NormalizeOption{ReplacePath("/home/me/app", "$APP")},
+			"main.go:N main.run\n\t$GOROOT/src/runtime/proc.go:N +0xADDR\n$APP/x.go:N",
+		},
+	}
+	for _, c := range cases {
+		if actual := NormalizeStack(trace, c.opts...); actual != c.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", c.name, c.expected, actual)
+		}
+	}
+}
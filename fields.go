@@ -0,0 +1,53 @@
+package errgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Field is a key-value pair of structured context attached to an error.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// WithField attaches a key-value pair to the error, replacing any earlier
+// value for the same key, and returns the error. If the error is frozen,
+// a copy with the field set is returned instead.
+func (err *StackableError) WithField(key string, value interface{}) *StackableError {
+	if err.frozen {
+		err = err.clone()
+	}
+	for i, field := range err.fields {
+		if field.Key == key {
+			err.fields[i].Value = value
+			return err
+		}
+	}
+	err.fields = append(err.fields, Field{Key: key, Value: value})
+	return err
+}
+
+// Fields returns the fields attached to the error, in the order they were
+// first set.
+func (err *StackableError) Fields() []Field {
+	return err.fields
+}
+
+// Field returns the value attached to the error under key.
+func (err *StackableError) Field(key string) (interface{}, bool) {
+	for _, field := range err.fields {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (err *StackableError) fieldLines() string {
+	var b strings.Builder
+	for _, field := range err.fields {
+		fmt.Fprintf(&b, "%s=%v\n", field.Key, field.Value)
+	}
+	return b.String()
+}
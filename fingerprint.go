@@ -0,0 +1,24 @@
+package errgo
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Fingerprint returns a short hash identifying the kind of failure this
+// error represents. It is computed from the code, the type of the
+// underlying error and the functions on the stack, so it stays the same
+// for errors created at the same place even if their messages differ or
+// line numbers shift. Errors decoded by DecodeFromHeaders keep the
+// fingerprint they were sent with.
+func (err *StackableError) Fingerprint() string {
+	if err.fingerprint != "" {
+		return err.fingerprint
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\n%T\n", err.Code, err.Err)
+	for _, frame := range err.StackFrames() {
+		fmt.Fprintf(h, "%s.%s\n", frame.Package, frame.FunctionName)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,52 @@
+package errgo
+
+import (
+	"bytes"
+	"context"
+)
+
+type goOriginKey struct{}
+
+// goOrigin is the place a goroutine was started from by GoWithOrigin,
+// along with the origin of the goroutine that started it, if known.
+type goOrigin struct {
+	stack  []uintptr
+	frames *frameCache
+	parent *goOrigin
+}
+
+// GoWithOrigin runs fn in a new goroutine, recording the stack of the
+// caller in the context passed to fn. Errors made from that context with
+// WrapContext list where the goroutine was started from, and where its
+// ancestors were started from, below their own stack.
+func GoWithOrigin(ctx context.Context, fn func(ctx context.Context)) {
+	origin := &goOrigin{
+		stack:  captureStack(1),
+		frames: newFrameCache(nil),
+	}
+	origin.parent, _ = ctx.Value(goOriginKey{}).(*goOrigin)
+
+	go fn(context.WithValue(ctx, goOriginKey{}, origin))
+}
+
+// GoroutineOrigins returns, for each goroutine ancestor recorded with
+// GoWithOrigin, the stack it was started from, innermost first.
+func (err *StackableError) GoroutineOrigins() [][]StackFrame {
+	var origins [][]StackFrame
+	for origin := err.origin; origin != nil; origin = origin.parent {
+		origins = append(origins, origin.frames.get(origin.stack))
+	}
+	return origins
+}
+
+func (err *StackableError) originStack() string {
+	buf := bytes.Buffer{}
+	for _, frames := range err.GoroutineOrigins() {
+		if len(frames) == 0 {
+			continue
+		}
+		buf.WriteString("GOROUTINE STARTED AT:\n")
+		writeFrames(&buf, frames)
+	}
+	return buf.String()
+}
@@ -0,0 +1,68 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// wrapInGoroutines wraps err with WrapContext from depth nested goroutines
+// started with GoWithOrigin.
+func wrapInGoroutines(ctx context.Context, depth int, err error) *StackableError {
+	if depth == 0 {
+		return WrapContext(ctx, err)
+	}
+	ch := make(chan *StackableError)
+	GoWithOrigin(ctx, func(ctx context.Context) {
+		ch <- wrapInGoroutines(ctx, depth-1, err)
+	})
+	return <-ch
+}
+
+func TestGoroutineOrigins(t *testing.T) {
+	cases := []struct {
+		name    string
+		depth   int
+		origins int
+	}{
+		{"no goroutine", 0, 0},
+		{"one goroutine", 1, 1},
+		{"nested goroutines", 3, 3},
+	}
+	for _, c := range cases {
+		err := wrapInGoroutines(context.Background(), c.depth, errors.New("failed"))
+		origins := err.GoroutineOrigins()
+		if len(origins) != c.origins {
+			t.Errorf("%s: expected %d origins, got %d", c.name, c.origins, len(origins))
+		}
+		if actual := strings.Count(err.StackTrace(), "GOROUTINE STARTED AT:"); len(captureStack(0)) > 0 && actual != c.origins {
+			t.Errorf("%s: expected %d origin sections, got %d", c.name, c.origins, actual)
+		}
+	}
+}
+
+func TestGoroutineOriginFrames(t *testing.T) {
+	requireStacks(t)
+
+	err := wrapInGoroutines(context.Background(), 2, errors.New("failed"))
+	for i, frames := range err.GoroutineOrigins() {
+		if len(frames) == 0 {
+			t.Errorf("origin %d: expected frames", i)
+			continue
+		}
+		if frames[0].FunctionName != "wrapInGoroutines" {
+			t.Errorf("origin %d: expected to start in wrapInGoroutines, got %s", i, frames[0].FunctionName)
+		}
+	}
+}
+
+func TestGoroutineOriginKept(t *testing.T) {
+	ctx := context.Background()
+	inner := wrapInGoroutines(ctx, 1, errors.New("failed"))
+	outer := wrapInGoroutines(ctx, 2, inner)
+
+	if actual := len(outer.GoroutineOrigins()); actual != 1 {
+		t.Errorf("expected the origins of the first wrap to be kept, got %d origins", actual)
+	}
+}
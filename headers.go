@@ -0,0 +1,82 @@
+package errgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Header names used by EncodeToHeaders and DecodeFromHeaders.
+const (
+	HeaderMessage     = "errgo-message"
+	HeaderCode        = "errgo-code"
+	HeaderKind        = "errgo-kind"
+	HeaderFingerprint = "errgo-fingerprint"
+	HeaderTrace       = "errgo-trace"
+)
+
+// EncodeToHeaders stores err in a message header carrier, such as the
+// headers of a Kafka record or an AMQP message, so it survives being sent
+// to a retry or dead-letter queue. It writes the message, and if there is
+// a *StackableError in the chain of err, the code, kind name (such as
+// "NotExist"), fingerprint and stack frames, as JSON, of the outermost
+// one.
+func EncodeToHeaders(err error, headers map[string][]byte) error {
+	headers[HeaderMessage] = []byte(err.Error())
+
+	var e *StackableError
+	if !errors.As(err, &e) || e == nil {
+		return nil
+	}
+	if e.Code != "" {
+		headers[HeaderCode] = []byte(e.Code)
+	}
+	if kind := KindOf(e); kind != Other && kind.Name() != "" {
+		headers[HeaderKind] = []byte(kind.Name())
+	}
+	headers[HeaderFingerprint] = []byte(e.Fingerprint())
+
+	trace, jsonErr := json.Marshal(e.StackFrames())
+	if jsonErr != nil {
+		return jsonErr
+	}
+	headers[HeaderTrace] = trace
+	return nil
+}
+
+// DecodeFromHeaders rebuilds an error stored by EncodeToHeaders. The
+// result has the original message, code, kind and fingerprint, and the
+// original stack frames in place of its own. The bool is false if the
+// headers hold no error.
+func DecodeFromHeaders(headers map[string][]byte) (*StackableError, bool, error) {
+	msg, ok := headers[HeaderMessage]
+	if !ok {
+		return nil, false, nil
+	}
+
+	var frames []StackFrame
+	if trace, ok := headers[HeaderTrace]; ok {
+		if err := json.Unmarshal(trace, &frames); err != nil {
+			return nil, false, err
+		}
+	}
+	if frames == nil {
+		frames = []StackFrame{}
+	}
+
+	e := &StackableError{
+		Err:         errors.New(string(msg)),
+		Code:        string(headers[HeaderCode]),
+		stack:       framesStack(frames),
+		frames:      newFrameCache(frames),
+		fingerprint: string(headers[HeaderFingerprint]),
+	}
+	if name, ok := headers[HeaderKind]; ok {
+		kind, ok := ParseKind(string(name))
+		if !ok {
+			return nil, false, fmt.Errorf("errgo: unknown kind %q in %s header", name, HeaderKind)
+		}
+		e.Kind = kind
+	}
+	return created(e), true, nil
+}
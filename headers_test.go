@@ -0,0 +1,71 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHeadersRoundTrip(t *testing.T) {
+	inner := E("orders.Load", NotExist, errors.New("missing"))
+	inner.Code = "order_missing"
+
+	cases := map[string]error{
+		"StackableError": inner,
+		"wrapped":        fmt.Errorf("consume: %w", inner),
+	}
+	for name, err := range cases {
+		headers := map[string][]byte{}
+		if encodeErr := EncodeToHeaders(err, headers); encodeErr != nil {
+			t.Fatalf("%s: %v", name, encodeErr)
+		}
+		if kind := string(headers[HeaderKind]); kind != "NotExist" {
+			t.Errorf("%s: expected kind header NotExist, got %q", name, kind)
+		}
+
+		decoded, ok, decodeErr := DecodeFromHeaders(headers)
+		if !ok || decodeErr != nil {
+			t.Fatalf("%s: decoding failed: %v %v", name, ok, decodeErr)
+		}
+		if decoded.Error() != err.Error() || decoded.Code != "order_missing" || decoded.Kind != NotExist {
+			t.Errorf("%s: unexpected decoded error %#v", name, decoded)
+		}
+		if decoded.Fingerprint() != inner.Fingerprint() {
+			t.Errorf("%s: expected fingerprint %s, got %s", name, inner.Fingerprint(), decoded.Fingerprint())
+		}
+		if len(decoded.StackFrames()) != len(inner.StackFrames()) {
+			t.Errorf("%s: expected %d frames, got %d", name, len(inner.StackFrames()), len(decoded.StackFrames()))
+		}
+	}
+}
+
+func TestHeadersPlainError(t *testing.T) {
+	headers := map[string][]byte{}
+	EncodeToHeaders(errors.New("plain"), headers)
+	if len(headers) != 1 || string(headers[HeaderMessage]) != "plain" {
+		t.Errorf("expected only the message header, got %v", headers)
+	}
+
+	if _, ok, _ := DecodeFromHeaders(map[string][]byte{}); ok {
+		t.Error("decoded an error from empty headers")
+	}
+	if _, _, err := DecodeFromHeaders(map[string][]byte{HeaderMessage: []byte("x"), HeaderKind: []byte("3")}); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
+
+func TestDecodedErrorPublishedComplete(t *testing.T) {
+	headers := map[string][]byte{}
+	err := E("op", Permission, nil)
+	err.Code = "denied"
+	EncodeToHeaders(err, headers)
+
+	s := Subscribe(SubscribeOptions{})
+	defer s.Close()
+	DecodeFromHeaders(headers)
+
+	published := <-s.C
+	if published.Code != "denied" || published.Kind != Permission {
+		t.Errorf("subscribers got code=%q kind=%v", published.Code, published.Kind)
+	}
+}
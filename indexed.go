@@ -0,0 +1,97 @@
+package errgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IndexedErrors records the outcome of each item of a batch operation,
+// keyed by the item's index or key, so that partial failures can be
+// reported item by item. The zero value is ready to use.
+type IndexedErrors struct {
+	keys []interface{}
+	errs map[interface{}]*StackableError
+}
+
+// Add records the outcome for the item with the given key. A nil err
+// marks the item as succeeded; any other value is wrapped like Wrap does,
+// so a typed nil pointer counts as a failure.
+// Adding the same key again replaces its outcome.
+func (ie *IndexedErrors) Add(key interface{}, err error) {
+	if ie.errs == nil {
+		ie.errs = map[interface{}]*StackableError{}
+	}
+	if _, ok := ie.errs[key]; !ok {
+		ie.keys = append(ie.keys, key)
+	}
+
+	if err == nil {
+		ie.errs[key] = nil
+		return
+	}
+	ie.errs[key] = wrap(err, 1)
+}
+
+// Len returns the number of items recorded.
+func (ie *IndexedErrors) Len() int {
+	return len(ie.keys)
+}
+
+// Failed returns the keys of the items that failed, in the order they were
+// added.
+func (ie *IndexedErrors) Failed() []interface{} {
+	var keys []interface{}
+	for _, key := range ie.keys {
+		if ie.errs[key] != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Succeeded returns the keys of the items that succeeded, in the order
+// they were added.
+func (ie *IndexedErrors) Succeeded() []interface{} {
+	var keys []interface{}
+	for _, key := range ie.keys {
+		if ie.errs[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ByKey returns the error recorded for key, or nil if the item succeeded
+// or was never added.
+func (ie *IndexedErrors) ByKey(key interface{}) *StackableError {
+	return ie.errs[key]
+}
+
+// Error lists the failed items and their messages.
+func (ie *IndexedErrors) Error() string {
+	failed := ie.Failed()
+	msgs := make([]string, len(failed))
+	for i, key := range failed {
+		msgs[i] = fmt.Sprintf("%v: %s", key, ie.errs[key].Error())
+	}
+	return fmt.Sprintf("%d of %d items failed: %s", len(failed), len(ie.keys), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed items, for use with errors.Is
+// and errors.As.
+func (ie *IndexedErrors) Unwrap() []error {
+	var errs []error
+	for _, key := range ie.Failed() {
+		errs = append(errs, ie.errs[key])
+	}
+	return errs
+}
+
+// ErrorOrNil returns ie if any item failed, and nil otherwise, so it can
+// be returned directly as an error.
+func (ie *IndexedErrors) ErrorOrNil() error {
+	if len(ie.Failed()) == 0 {
+		return nil
+	}
+	return ie
+}
@@ -0,0 +1,98 @@
+package errgo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// quotaError is converted by the converter registered below.
+type quotaError struct{}
+
+func (quotaError) Error() string { return "over quota" }
+
+func init() {
+	RegisterConverter(func(err error) (*StackableError, bool) {
+		if _, ok := err.(quotaError); ok {
+			return &StackableError{Err: err, Code: "indexed_test.quota", Kind: Transient}, true
+		}
+		return nil, false
+	})
+}
+
+func TestIndexedErrors(t *testing.T) {
+	var nilErr *StackableError
+	base := errors.New("boom")
+
+	var ie IndexedErrors
+	ie.Add("a", nil)
+	ie.Add("b", base)
+	ie.Add("c", nilErr)
+	ie.Add("d", quotaError{})
+	ie.Add("e", nil)
+	ie.Add("a", WrapPrefix(base, "retried"))
+
+	cases := []struct {
+		key     string
+		failed  bool
+		code    string
+		message string
+	}{
+		{"a", true, "", "retried: boom"},
+		{"b", true, "", "boom"},
+		{"c", true, "", "typed nil"},
+		{"d", true, "indexed_test.quota", "over quota"},
+		{"e", false, "", ""},
+		{"missing", false, "", ""},
+	}
+	for _, c := range cases {
+		err := ie.ByKey(c.key)
+		if (err != nil) != c.failed {
+			t.Errorf("%s: expected failed=%v, got %v", c.key, c.failed, err)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		if !strings.Contains(err.Error(), c.message) {
+			t.Errorf("%s: expected a message containing %q, got %q", c.key, c.message, err.Error())
+		}
+		if err.Code != c.code {
+			t.Errorf("%s: expected code %q, got %q", c.key, c.code, err.Code)
+		}
+	}
+
+	if ie.Len() != 5 {
+		t.Errorf("expected 5 items, got %d", ie.Len())
+	}
+	if failed := ie.Failed(); len(failed) != 4 || failed[0] != "a" || failed[3] != "d" {
+		t.Errorf("expected a, b, c and d to fail in order, got %v", failed)
+	}
+	if succeeded := ie.Succeeded(); len(succeeded) != 1 || succeeded[0] != "e" {
+		t.Errorf("expected e to succeed, got %v", succeeded)
+	}
+	if !strings.HasPrefix(ie.Error(), "4 of 5 items failed: a: retried: boom; b: boom;") {
+		t.Errorf("unexpected message %q", ie.Error())
+	}
+	if !errors.Is(ie.ErrorOrNil(), base) {
+		t.Error("expected the failed items to be unwrapped")
+	}
+}
+
+func TestIndexedErrorsNone(t *testing.T) {
+	var ie IndexedErrors
+	ie.Add(1, nil)
+	if err := ie.ErrorOrNil(); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestIndexedErrorsStack(t *testing.T) {
+	requireStacks(t)
+	var ie IndexedErrors
+	ie.Add(0, errors.New("x"))
+	frames := ie.ByKey(0).StackFrames()
+	if !strings.HasSuffix(frames[0].FunctionName, "TestIndexedErrorsStack") {
+		t.Errorf("expected the stack to start at the caller of Add, got %s", frames[0].FunctionName)
+	}
+}
@@ -0,0 +1,293 @@
+package errgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncPolicy controls when a Journal flushes its file to disk.
+type SyncPolicy int
+
+const (
+	// SyncNone leaves flushing to the operating system.
+	SyncNone SyncPolicy = iota
+	// SyncAlways calls fsync after every entry.
+	SyncAlways
+	// SyncPeriodic calls fsync after an entry if SyncInterval has passed
+	// since the last sync.
+	SyncPeriodic
+)
+
+// JournalOptions configure a Journal. Zero fields take the defaults noted
+// below.
+type JournalOptions struct {
+	MaxSize      int64         // bytes per file before rotating, default 10 MiB
+	MaxFiles     int           // rotated files kept besides the current one, default 5
+	Sync         SyncPolicy    // default SyncNone
+	SyncInterval time.Duration // for SyncPeriodic, default 1s
+}
+
+// A JournalEntry is one error recorded in a Journal.
+type JournalEntry struct {
+	Time        time.Time              `json:"time"`
+	Message     string                 `json:"message"`
+	Code        string                 `json:"code,omitempty"`
+	Kind        string                 `json:"kind,omitempty"`
+	Severity    string                 `json:"severity,omitempty"`
+	Fingerprint string                 `json:"fingerprint,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Notes       []string               `json:"notes,omitempty"`
+	Stack       []StackFrame           `json:"stack,omitempty"`
+	Causes      []string               `json:"causes,omitempty"`
+}
+
+// NewJournalEntry describes err as a JournalEntry. The entry's time is
+// when the outermost StackableError in the chain was created, or the
+// current time if there is none. The code, severity, fields, notes and
+// stack are those of the outermost StackableError in the chain, and
+// Causes lists the messages of the errors it wraps.
+func NewJournalEntry(err error) JournalEntry {
+	entry := JournalEntry{Time: now(), Message: err.Error()}
+	if kind := KindOf(err); kind != Other {
+		entry.Kind = kind.String()
+	}
+	if severity := SeverityOf(err); severity != SeverityUnset {
+		entry.Severity = severity.String()
+	}
+
+	var e *StackableError
+	if errors.As(err, &e) && e != nil {
+		if !e.timestamp.IsZero() {
+			entry.Time = e.timestamp
+		}
+		entry.Code = e.Code
+		entry.Fingerprint = e.Fingerprint()
+		entry.Notes = e.notes
+		entry.Stack = e.StackFrames()
+		for _, field := range e.fields {
+			if entry.Fields == nil {
+				entry.Fields = map[string]interface{}{}
+			}
+			entry.Fields[field.Key] = field.Value
+		}
+	}
+
+	causes, _ := walkChain(err)
+	for _, cause := range causes[1:] {
+		entry.Causes = append(entry.Causes, cause.Error())
+	}
+	return entry
+}
+
+// Journal appends errors as JSON lines to a file, rotating it when it grows
+// past MaxSize, so error history is kept without an external tracker. It
+// is safe for concurrent use and implements Reporter.
+type Journal struct {
+	path string
+	opts JournalOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	synced time.Time
+}
+
+// OpenJournal opens the journal at path, creating the file if needed.
+// Rotated files are named path.1 (newest) to path.N.
+func OpenJournal(path string, opts JournalOptions) (*Journal, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 10 << 20
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = 5
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = time.Second
+	}
+	j := &Journal{path: path, opts: opts}
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *Journal) open() error {
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	size := info.Size()
+
+	// A crash during a write can leave a torn last line; end it, so the
+	// next entry starts on a line of its own.
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			f.Close()
+			return err
+		}
+		if last[0] != '\n' {
+			n, err := f.Write([]byte{'\n'})
+			size += int64(n)
+			if err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+
+	j.file, j.size = f, size
+	return nil
+}
+
+// Write appends err to the journal.
+func (j *Journal) Write(err error) error {
+	line, jsonErr := json.Marshal(NewJournalEntry(err))
+	if jsonErr != nil {
+		return jsonErr
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return os.ErrClosed
+	}
+	var rotateErr error
+	if j.size > 0 && j.size+int64(len(line)) > j.opts.MaxSize {
+		// A failed rotation is reported, but the entry is still written
+		// if a file could be reopened.
+		if rotateErr = j.rotate(); j.file == nil {
+			return rotateErr
+		}
+	}
+	n, writeErr := j.file.Write(line)
+	j.size += int64(n)
+	if writeErr != nil {
+		return writeErr
+	}
+	if rotateErr != nil {
+		return rotateErr
+	}
+
+	switch j.opts.Sync {
+	case SyncAlways:
+		return j.file.Sync()
+	case SyncPeriodic:
+		if t := now(); t.Sub(j.synced) >= j.opts.SyncInterval {
+			j.synced = t
+			return j.file.Sync()
+		}
+	}
+	return nil
+}
+
+// Report implements Reporter by writing err to the journal. Write errors
+// are dropped; use Write to handle them.
+func (j *Journal) Report(ctx context.Context, err error) {
+	j.Write(err)
+}
+
+// rotate shifts path.N-1 to path.N, ..., path to path.1 and starts a new
+// file. The oldest file beyond MaxFiles is removed. If rotating fails, the
+// journal goes on writing to whatever file is at path.
+func (j *Journal) rotate() error {
+	err := j.file.Close()
+	j.file = nil
+	if err == nil {
+		os.Remove(fmt.Sprintf("%s.%d", j.path, j.opts.MaxFiles))
+		for i := j.opts.MaxFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", j.path, i), fmt.Sprintf("%s.%d", j.path, i+1))
+		}
+		err = os.Rename(j.path, j.path+".1")
+	}
+	if openErr := j.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// Sync flushes the journal file to disk.
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return os.ErrClosed
+	}
+	return j.file.Sync()
+}
+
+// Close syncs and closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return os.ErrClosed
+	}
+	syncErr := j.file.Sync()
+	closeErr := j.file.Close()
+	j.file = nil
+	if syncErr != nil {
+		return syncErr
+	}
+	return closeErr
+}
+
+// ReadJournal calls fn with every entry of the journal at path and its
+// rotated files, oldest first, stopping at the first error fn returns.
+// Lines that cannot be decoded, such as one torn by a crash during a
+// write, are skipped.
+func ReadJournal(path string, fn func(JournalEntry) error) error {
+	var files []string
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		files = append([]string{name}, files...)
+	}
+	files = append(files, path)
+
+	for _, name := range files {
+		if err := readJournalFile(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readJournalFile(name string, fn func(JournalEntry) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,145 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	// Every entry is larger than MaxSize, so each file holds one entry.
+	j, err := OpenJournal(path, JournalOptions{MaxSize: 1, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := j.Write(fmt.Errorf("error %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only %d rotated files, stat of the next one gave %v", 2, err)
+	}
+
+	// A crash during a write can leave a truncated last line.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-01-01T00:00:00Z","mess`)
+	f.Close()
+
+	var messages []string
+	err = ReadJournal(path, func(entry JournalEntry) error {
+		messages = append(messages, entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"error 2", "error 3", "error 4"}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+}
+
+func TestJournalEntry(t *testing.T) {
+	requireStacks(t)
+	cause := errors.New("missing")
+	err := E("db.Get", NotExist, cause).WithField("id", 7)
+	err.Code = "E404"
+
+	entry := NewJournalEntry(fmt.Errorf("load: %w", err))
+	if entry.Code != "E404" || entry.Kind != NotExist.String() || entry.Fingerprint != err.Fingerprint() {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.Fields["id"] != 7 || len(entry.Stack) == 0 || !entry.Time.Equal(err.Time()) {
+		t.Errorf("entry lost fields, stack or time: %+v", entry)
+	}
+	if len(entry.Causes) != 2 || entry.Causes[1] != "missing" {
+		t.Errorf("expected the wrapped messages as causes, got %v", entry.Causes)
+	}
+}
+
+func readMessages(t *testing.T, read func(func(JournalEntry) error) error) []string {
+	t.Helper()
+	var messages []string
+	err := read(func(entry JournalEntry) error {
+		messages = append(messages, entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func TestJournalReopenAfterTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	write := func(messages ...string) {
+		j, err := OpenJournal(path, JournalOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range messages {
+			if err := j.Write(errors.New(msg)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		j.Close()
+	}
+
+	write("before")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-01-01T00:00:00Z","mess`)
+	f.Close()
+	write("after 1", "after 2")
+
+	messages := readMessages(t, func(fn func(JournalEntry) error) error { return ReadJournal(path, fn) })
+	expected := []string{"before", "after 1", "after 2"}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+}
+
+func TestJournalRotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	// A non-empty directory in the way of path.1 makes rotating fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "busy"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := OpenJournal(path, JournalOptions{MaxSize: 1, MaxFiles: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if err := j.Write(errors.New("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Write(errors.New("second")); err == nil {
+		t.Error("expected the failed rotation to be reported")
+	}
+	if err := j.Write(errors.New("third")); err == nil {
+		t.Error("expected the failed rotation to be reported")
+	}
+	if err := j.Sync(); err != nil {
+		t.Fatalf("the journal was closed by the failed rotation: %v", err)
+	}
+
+	messages := readMessages(t, func(fn func(JournalEntry) error) error { return readJournalFile(path, fn) })
+	expected := []string{"first", "second", "third"}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+}
@@ -0,0 +1,46 @@
+package errgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "\n", " ",
+)
+
+// MarkdownTrace formats the error as Markdown for alerts and issue
+// trackers: the message in bold, its code, and a numbered list of frames,
+// each linked to its line of source when StackFrame.SourceURL gives one:
+//
+//	**handler: missing**
+//
+//	Code: `not_found`
+//
+//	1. `main.handler` at [cmd/app/main.go:10](https://github.com/org/repo/blob/abc123/cmd/app/main.go#L10)
+//	2. `runtime.main` at `runtime/proc.go:283`
+func (err *StackableError) MarkdownTrace() string {
+	buf := bytes.Buffer{}
+	buf.WriteString("**" + markdownEscaper.Replace(err.Error()) + "**\n\n")
+	if err.Code != "" {
+		buf.WriteString("Code: `" + err.Code + "`\n\n")
+	}
+
+	for i, frame := range err.StackFrames() {
+		name := frame.FunctionName
+		if frame.Package != "" {
+			name = frame.Package + "." + name
+		}
+		location := fmt.Sprintf("%s:%d", RelativeFilePath(frame.File), frame.LineNumber)
+		if url := frame.SourceURL(); url != "" {
+			location = "[" + markdownEscaper.Replace(location) + "](" + url + ")"
+		} else {
+			location = "`" + location + "`"
+		}
+		fmt.Fprintf(&buf, "%d. `%s` at %s\n", i+1, name, location)
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,40 @@
+package errgo
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownTrace(t *testing.T) {
+	requireStacks(t)
+	err := WrapPrefix(errors.New("a *bold* [link]"), "load")
+	err.Code = "not_found"
+	frame := err.StackFrames()[0]
+
+	defer func(template, root string) { SourceURLTemplate, SourceRoot = template, root }(SourceURLTemplate, SourceRoot)
+
+	cases := []struct {
+		name     string
+		template string
+		frame    string
+	}{
+		{"without links", "", "1. `github.com/freemish/errgo.TestMarkdownTrace` at `" + RelativeFilePath(frame.File)},
+		{"with links", "https://example.com/{path}#L{line}", "1. `github.com/freemish/errgo.TestMarkdownTrace` at [" + strings.Replace(RelativeFilePath(frame.File), "_", `\_`, -1)},
+	}
+	for _, c := range cases {
+		SourceURLTemplate, SourceRoot = c.template, filepath.Dir(frame.File)
+		trace := err.MarkdownTrace()
+
+		if !strings.HasPrefix(trace, "**load: a \\*bold\\* \\[link\\]**\n\nCode: `not_found`\n\n") {
+			t.Errorf("%s: unexpected header in\n%s", c.name, trace)
+		}
+		if !strings.Contains(trace, c.frame) {
+			t.Errorf("%s: expected %q in\n%s", c.name, c.frame, trace)
+		}
+		if url := frame.SourceURL(); c.template != "" && !strings.Contains(trace, "("+url+")") {
+			t.Errorf("%s: expected a link to %s in\n%s", c.name, url, trace)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package errgo
+
+import "strings"
+
+// Match reports whether pred is true for err or any error it wraps,
+// including each error joined with errors.Join or held by a multi-error.
+// It is for matching on properties where no sentinel or type is available,
+// e.g. Match(err, ByCode("quota_exceeded")).
+func Match(err error, pred func(error) bool) bool {
+	return match(err, pred, 0)
+}
+
+func match(err error, pred func(error) bool, depth int) bool {
+	if depth >= MaxChainDepth {
+		return false
+	}
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if pred(err) {
+			return true
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				if match(err, pred, depth+1) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ByKind matches a StackableError of the given kind.
+func ByKind(kind Kind) func(error) bool {
+	return func(err error) bool {
+		e, ok := err.(*StackableError)
+		return ok && e.Kind == kind
+	}
+}
+
+// ByCode matches a StackableError with the given code.
+func ByCode(code string) func(error) bool {
+	return func(err error) bool {
+		e, ok := err.(*StackableError)
+		return ok && e.Code == code
+	}
+}
+
+// MessageContains matches an error whose message contains substr. The
+// message of a wrapper includes those of the errors it wraps, so with
+// Match this matches if the text appears anywhere in the chain.
+func MessageContains(substr string) func(error) bool {
+	return func(err error) bool {
+		return strings.Contains(err.Error(), substr)
+	}
+}
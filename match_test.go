@@ -0,0 +1,36 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	coded := E("quota.Check", Transient, nil, "over quota")
+	coded.Code = "quota_exceeded"
+	wrapped := fmt.Errorf("api: %w", coded)
+
+	cases := []struct {
+		name     string
+		err      error
+		pred     func(error) bool
+		expected bool
+	}{
+		{"kind", wrapped, ByKind(Transient), true},
+		{"other kind", wrapped, ByKind(Invalid), false},
+		{"code", wrapped, ByCode("quota_exceeded"), true},
+		{"other code", wrapped, ByCode("rate_limited"), false},
+		{"message of the wrapper", wrapped, MessageContains("api: quota.Check"), true},
+		{"message of the cause", wrapped, MessageContains("over quota"), true},
+		{"missing message", wrapped, MessageContains("rate"), false},
+		{"joined", errors.Join(errors.New("a"), wrapped), ByCode("quota_exceeded"), true},
+		{"multi-error", Append(errors.New("a"), coded), ByKind(Transient), true},
+		{"nil", nil, MessageContains(""), false},
+	}
+	for _, c := range cases {
+		if actual := Match(c.err, c.pred); actual != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package errgo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError holds several errors, each with its own stack. Besides
+// Unwrap() []error it has the WrappedErrors method of
+// hashicorp/go-multierror and the Errors method of uber-go/multierr, so
+// code written against those libraries sees its errors.
+type MultiError struct {
+	errs []*StackableError
+}
+
+// Flatten collects the errors held by err into a MultiError. Aggregates
+// are recognised by their Unwrap() []error, WrappedErrors() []error (as
+// in hashicorp/go-multierror) or Errors() []error (as in uber-go/multierr)
+// methods and flattened recursively. Leaves that are not already a
+// StackableError are wrapped with the stack of the caller. Flatten returns
+// nil if there are no errors.
+func Flatten(errs ...error) *MultiError {
+	m := &MultiError{}
+	var cache *frameCache
+	stack := captureStack(1)
+	for _, err := range errs {
+		m.flatten(err, stack, &cache, 0)
+	}
+	if len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Append is like Flatten, and returns nil, rather than a nil *MultiError,
+// if there are no errors, so the result can be returned as an error.
+func Append(err error, errs ...error) error {
+	m := &MultiError{}
+	var cache *frameCache
+	stack := captureStack(1)
+	m.flatten(err, stack, &cache, 0)
+	for _, err := range errs {
+		m.flatten(err, stack, &cache, 0)
+	}
+	return m.ErrorOrNil()
+}
+
+func (m *MultiError) flatten(err error, stack []uintptr, cache **frameCache, depth int) {
+	if err == nil {
+		return
+	}
+	if children, ok := aggregated(err); ok && depth < MaxChainDepth {
+		for _, child := range children {
+			m.flatten(child, stack, cache, depth+1)
+		}
+		return
+	}
+	if e, ok := err.(*StackableError); ok {
+		m.errs = append(m.errs, e)
+		return
+	}
+	if *cache == nil {
+		*cache = newFrameCache(nil)
+	}
+	m.errs = append(m.errs, created(&StackableError{Err: err, stack: stack, frames: *cache}))
+}
+
+// aggregated returns the errors held by err if it is an aggregate.
+func aggregated(err error) ([]error, bool) {
+	switch err := err.(type) {
+	case interface{ WrappedErrors() []error }:
+		return err.WrappedErrors(), true
+	case interface{ Errors() []error }:
+		return err.Errors(), true
+	case interface{ Unwrap() []error }:
+		return err.Unwrap(), true
+	}
+	return nil, false
+}
+
+// Error lists the messages of the errors.
+func (m *MultiError) Error() string {
+	if len(m.errs) == 1 {
+		return m.errs[0].Error()
+	}
+	msgs := make([]string, len(m.errs))
+	for i, err := range m.errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m.errs), strings.Join(msgs, "; "))
+}
+
+// Len returns the number of errors.
+func (m *MultiError) Len() int {
+	return len(m.errs)
+}
+
+// Unwrap returns the errors, for use with errors.Is and errors.As.
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, len(m.errs))
+	for i, err := range m.errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// WrappedErrors returns the errors, as hashicorp/errwrap and
+// go-multierror expect.
+func (m *MultiError) WrappedErrors() []error {
+	return m.Unwrap()
+}
+
+// Errors returns the errors, as uber-go/multierr's Errors function
+// expects.
+func (m *MultiError) Errors() []error {
+	return m.Unwrap()
+}
+
+// ErrorOrNil returns m if it holds any errors, and nil otherwise.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
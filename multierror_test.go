@@ -0,0 +1,104 @@
+package errgo
+
+import (
+	"errors"
+	"testing"
+)
+
+// hashicorpError and uberError have the methods go-multierror and
+// multierr use to expose their errors.
+type hashicorpError []error
+
+func (e hashicorpError) Error() string          { return "hashicorp" }
+func (e hashicorpError) WrappedErrors() []error { return e }
+
+type uberError []error
+
+func (e uberError) Error() string   { return "uber" }
+func (e uberError) Errors() []error { return e }
+
+func TestFlatten(t *testing.T) {
+	a, b, c := errors.New("a"), errors.New("b"), errors.New("c")
+	stackable := E("op", NotExist, nil)
+
+	cases := []struct {
+		name     string
+		errs     []error
+		expected []string
+	}{
+		{"none", nil, nil},
+		{"nils", []error{nil, nil}, nil},
+		{"plain", []error{a, nil, b}, []string{"a", "b"}},
+		{"errors.Join", []error{errors.Join(a, b), c}, []string{"a", "b", "c"}},
+		{"go-multierror", []error{hashicorpError{a, uberError{b, c}}}, []string{"a", "b", "c"}},
+		{"multierr", []error{uberError{a, nil, b}}, []string{"a", "b"}},
+		{"MultiError", []error{Flatten(a, b), c}, []string{"a", "b", "c"}},
+		{"StackableError", []error{stackable}, []string{"op: item does not exist"}},
+	}
+	for _, c := range cases {
+		m := Flatten(c.errs...)
+		if c.expected == nil {
+			if m != nil {
+				t.Errorf("%s: expected nil, got %v", c.name, m)
+			}
+			continue
+		}
+		if m.Len() != len(c.expected) {
+			t.Errorf("%s: expected %d errors, got %d", c.name, len(c.expected), m.Len())
+			continue
+		}
+		for i, err := range m.Unwrap() {
+			if err.Error() != c.expected[i] {
+				t.Errorf("%s: expected error %d to be %q, got %q", c.name, i, c.expected[i], err.Error())
+			}
+			if _, ok := err.(*StackableError); !ok {
+				t.Errorf("%s: expected error %d to be a StackableError, got %T", c.name, i, err)
+			}
+		}
+	}
+
+	if m := Flatten(stackable); m.Unwrap()[0] != stackable {
+		t.Error("expected a StackableError to be kept as is")
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	a, b := errors.New("a"), errors.New("b")
+
+	if err := Append(nil, nil); err != nil {
+		t.Errorf("expected Append of nils to be nil, got %v", err)
+	}
+	if err := Append(a); err.Error() != "a" {
+		t.Errorf("expected a single error's message, got %q", err.Error())
+	}
+
+	err := Append(a, b)
+	if err.Error() != "2 errors occurred: a; b" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if !errors.Is(err, a) || !errors.Is(err, b) {
+		t.Error("expected errors.Is to find both errors")
+	}
+	m := err.(*MultiError)
+	if len(m.WrappedErrors()) != 2 || len(m.Errors()) != 2 {
+		t.Error("expected the go-multierror and multierr accessors to return both errors")
+	}
+
+	var nilMulti *MultiError
+	if nilMulti.ErrorOrNil() != nil || (&MultiError{}).ErrorOrNil() != nil {
+		t.Error("expected ErrorOrNil to be nil without errors")
+	}
+}
+
+func TestFlattenStack(t *testing.T) {
+	requireStacks(t)
+	m := Flatten(errors.New("a"), errors.New("b"))
+	errs := m.Unwrap()
+	first, second := errs[0].(*StackableError), errs[1].(*StackableError)
+	if first.StackFrames()[0].FunctionName != "TestFlattenStack" {
+		t.Errorf("expected the stack of the caller, got %s", first.StackFrames()[0].FunctionName)
+	}
+	if &first.StackFrames()[0] != &second.StackFrames()[0] {
+		t.Error("expected the leaves to share their resolved frames")
+	}
+}
@@ -0,0 +1,107 @@
+package errgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// NDJSONOptions configure an NDJSONSink. The zero value writes each error
+// straight through to the writer and never rotates.
+type NDJSONOptions struct {
+	// BufferSize, if positive, buffers output in memory up to that many
+	// bytes. Buffered output is written by Flush and before rotating.
+	BufferSize int
+
+	// ShouldRotate is called after each error with the number of bytes
+	// written since the sink started or last rotated. If it returns true,
+	// the sink is flushed and Rotate is called.
+	ShouldRotate func(written int64) bool
+
+	// Rotate returns the writer to continue with, e.g. a new file, and
+	// may close the old one.
+	Rotate func(old io.Writer) (io.Writer, error)
+}
+
+// NDJSONSink writes errors to an io.Writer as newline-delimited JSON, one
+// JournalEntry per line, for environments without a logging library. It
+// is safe for concurrent use and implements Reporter.
+type NDJSONSink struct {
+	opts NDJSONOptions
+
+	mu      sync.Mutex
+	w       io.Writer
+	buf     *bufio.Writer
+	written int64
+}
+
+// NewNDJSONSink returns a sink writing to w.
+func NewNDJSONSink(w io.Writer, opts NDJSONOptions) *NDJSONSink {
+	s := &NDJSONSink{opts: opts}
+	s.reset(w)
+	return s
+}
+
+func (s *NDJSONSink) reset(w io.Writer) {
+	s.w, s.buf, s.written = w, nil, 0
+	if s.opts.BufferSize > 0 {
+		s.buf = bufio.NewWriterSize(w, s.opts.BufferSize)
+	}
+}
+
+// Write writes err as one line of JSON.
+func (s *NDJSONSink) Write(err error) error {
+	line, jsonErr := json.Marshal(NewJournalEntry(err))
+	if jsonErr != nil {
+		return jsonErr
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var w io.Writer = s.w
+	if s.buf != nil {
+		w = s.buf
+	}
+	n, writeErr := w.Write(line)
+	s.written += int64(n)
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if s.opts.ShouldRotate == nil || s.opts.Rotate == nil || !s.opts.ShouldRotate(s.written) {
+		return nil
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	next, rotateErr := s.opts.Rotate(s.w)
+	if rotateErr != nil {
+		return rotateErr
+	}
+	s.reset(next)
+	return nil
+}
+
+// Report implements Reporter by writing err. Write errors are dropped;
+// use Write to handle them.
+func (s *NDJSONSink) Report(ctx context.Context, err error) {
+	s.Write(err)
+}
+
+// Flush writes any buffered output to the writer.
+func (s *NDJSONSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *NDJSONSink) flush() error {
+	if s.buf == nil {
+		return nil
+	}
+	return s.buf.Flush()
+}
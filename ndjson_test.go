@@ -0,0 +1,106 @@
+package errgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// ndjsonMessages decodes the messages of the entries written to buf.
+func ndjsonMessages(t *testing.T, buf *bytes.Buffer) []string {
+	t.Helper()
+	var msgs []string
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		msgs = append(msgs, entry.Message)
+	}
+	return msgs
+}
+
+func TestNDJSONSink(t *testing.T) {
+	cases := []struct {
+		name     string
+		buffer   int
+		flushed  []string
+		expected []string
+	}{
+		{"unbuffered", 0, []string{"a", "db.Get: b", "c"}, []string{"a", "db.Get: b", "c"}},
+		{"buffered", 4096, nil, []string{"a", "db.Get: b", "c"}},
+	}
+	for _, c := range cases {
+		out := &bytes.Buffer{}
+		s := NewNDJSONSink(out, NDJSONOptions{BufferSize: c.buffer})
+		s.Write(errors.New("a"))
+		s.Write(E("db.Get", NotExist, nil, "b"))
+		s.Report(context.Background(), errors.New("c"))
+
+		if actual := ndjsonMessages(t, out); !reflect.DeepEqual(actual, c.flushed) {
+			t.Errorf("%s: expected %v before Flush, got %v", c.name, c.flushed, actual)
+		}
+		if err := s.Flush(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if actual := ndjsonMessages(t, out); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %v after Flush, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestNDJSONSinkRotation(t *testing.T) {
+	files := []*bytes.Buffer{{}}
+	s := NewNDJSONSink(files[0], NDJSONOptions{
+		BufferSize:   4096,
+		ShouldRotate: func(written int64) bool { return written > 60 },
+		Rotate: func(old io.Writer) (io.Writer, error) {
+			if old != files[len(files)-1] {
+				t.Error("expected Rotate to be given the current writer")
+			}
+			files = append(files, &bytes.Buffer{})
+			return files[len(files)-1], nil
+		},
+	})
+	for _, msg := range []string{"a", "b", "c"} {
+		if err := s.Write(errors.New(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Flush()
+
+	var actual [][]string
+	for _, f := range files {
+		actual = append(actual, ndjsonMessages(t, f))
+	}
+	// Each entry is longer than 60 bytes, so every write rotates.
+	expected := [][]string{{"a"}, {"b"}, {"c"}, nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNDJSONSinkRotateError(t *testing.T) {
+	out := &bytes.Buffer{}
+	rotateErr := errors.New("disk full")
+	s := NewNDJSONSink(out, NDJSONOptions{
+		ShouldRotate: func(written int64) bool { return true },
+		Rotate:       func(old io.Writer) (io.Writer, error) { return nil, rotateErr },
+	})
+
+	if err := s.Write(errors.New("a")); err != rotateErr {
+		t.Errorf("expected the rotate error, got %v", err)
+	}
+	if err := s.Write(errors.New("b")); err != rotateErr {
+		t.Errorf("expected the rotate error again, got %v", err)
+	}
+	if actual := ndjsonMessages(t, out); !reflect.DeepEqual(actual, []string{"a", "b"}) {
+		t.Errorf("expected the sink to keep the old writer, got %v", actual)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build errgo_nostack
+
+package errgo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNoStack(t *testing.T) {
+	cases := map[string]*StackableError{
+		"Wrap":       Wrap("boom"),
+		"WrapPrefix": WrapPrefix(errors.New("boom"), "prefix"),
+		"E":          E("op", NotExist, nil),
+		"Acquire":    Acquire("boom"),
+	}
+	for name, err := range cases {
+		if len(err.Callers()) != 0 || len(err.StackFrames()) != 0 || err.Stack() != "" {
+			t.Errorf("%s: expected no stack, got %v", name, err.StackFrames())
+		}
+	}
+
+	if err := WrapPrefix("boom", "prefix"); err.Error() != "prefix: boom" {
+		t.Errorf("expected the message and prefixes to be kept, got %q", err.Error())
+	}
+}
@@ -0,0 +1,22 @@
+package errgo
+
+import "errors"
+
+// AddNote records a note on the outermost StackableError in the chain of
+// err, for middleware that wants to attach an observation without
+// rewrapping the error or changing its message. Notes are printed in the
+// NOTES section of StackTrace(). It reports false, and does nothing, if
+// there is no StackableError in the chain or it is frozen.
+func AddNote(err error, note string) bool {
+	var e *StackableError
+	if !errors.As(err, &e) || e == nil || e.frozen {
+		return false
+	}
+	e.notes = append(e.notes, note)
+	return true
+}
+
+// Notes returns the notes added with AddNote, in order.
+func (err *StackableError) Notes() []string {
+	return err.notes
+}
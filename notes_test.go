@@ -0,0 +1,66 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddNote(t *testing.T) {
+	var nilErr *StackableError
+
+	cases := []struct {
+		name     string
+		err      func() (error, *StackableError)
+		added    bool
+		expected []string
+	}{
+		{"plain", func() (error, *StackableError) { return errors.New("boom"), nil }, false, nil},
+		{"typed nil", func() (error, *StackableError) { return nilErr, nil }, false, nil},
+		{
+			"stackable",
+			func() (error, *StackableError) { e := Wrap(errors.New("boom")); return e, e },
+			true,
+			[]string{"note"},
+		},
+		{
+			"wrapped",
+			func() (error, *StackableError) { e := Wrap(errors.New("boom")); return fmt.Errorf("load: %w", e), e },
+			true,
+			[]string{"note"},
+		},
+		{
+			"frozen",
+			func() (error, *StackableError) { e := Wrap(errors.New("boom")).Freeze(); return e, e },
+			false,
+			nil,
+		},
+	}
+	for _, c := range cases {
+		err, e := c.err()
+		if actual := AddNote(err, "note"); actual != c.added {
+			t.Errorf("%s: expected %v, got %v", c.name, c.added, actual)
+		}
+		if e != nil && !reflect.DeepEqual(e.Notes(), c.expected) {
+			t.Errorf("%s: expected notes %v, got %v", c.name, c.expected, e.Notes())
+		}
+	}
+}
+
+func TestNotesInTrace(t *testing.T) {
+	err := Wrap(errors.New("boom"))
+	if strings.Contains(err.StackTrace(), "NOTES:") {
+		t.Errorf("expected no NOTES section without notes, got:\n%s", err.StackTrace())
+	}
+
+	AddNote(err, "first")
+	AddNote(err, "second")
+	if !strings.Contains(err.StackTrace(), "NOTES:\nfirst\nsecond\n") {
+		t.Errorf("expected the notes in order, got:\n%s", err.StackTrace())
+	}
+	if err.Error() != "boom" {
+		t.Errorf("expected the message to be unchanged, got %q", err.Error())
+	}
+}
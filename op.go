@@ -0,0 +1,156 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Op describes the operation that failed, usually as "package.Function"
+// or "service.Method".
+type Op string
+
+// Kind classifies an error, e.g. as a permission or not-found failure.
+type Kind uint8
+
+// Kinds of errors. Other means the kind is unknown or unimportant.
+const (
+	Other      Kind = iota // Unclassified error.
+	Invalid                // Invalid operation or argument.
+	Permission             // Permission denied.
+	IO                     // External I/O error such as a network failure.
+	Exist                  // Item already exists.
+	NotExist               // Item does not exist.
+	Internal               // Internal error or inconsistency.
+	Transient              // Temporary failure; the operation may be retried.
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Other:
+		return "other error"
+	case Invalid:
+		return "invalid operation"
+	case Permission:
+		return "permission denied"
+	case IO:
+		return "I/O error"
+	case Exist:
+		return "item already exists"
+	case NotExist:
+		return "item does not exist"
+	case Internal:
+		return "internal error"
+	case Transient:
+		return "transient error"
+	}
+	return "unknown error kind"
+}
+
+// kindNames are the names of the Kind constants, indexed by Kind.
+var kindNames = [...]string{
+	Other:      "Other",
+	Invalid:    "Invalid",
+	Permission: "Permission",
+	IO:         "IO",
+	Exist:      "Exist",
+	NotExist:   "NotExist",
+	Internal:   "Internal",
+	Transient:  "Transient",
+}
+
+// Name returns the name of the constant for k, e.g. "NotExist", or "" if
+// k is not one of the kinds declared here.
+func (k Kind) Name() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return ""
+}
+
+// Kinds returns every kind declared here, in order.
+func Kinds() []Kind {
+	kinds := make([]Kind, len(kindNames))
+	for i := range kinds {
+		kinds[i] = Kind(i)
+	}
+	return kinds
+}
+
+// ParseKind returns the kind whose constant is named name, e.g.
+// "NotExist".
+func ParseKind(name string) (Kind, bool) {
+	for k, n := range kindNames {
+		if n == name {
+			return Kind(k), true
+		}
+	}
+	return Other, false
+}
+
+// E makes a StackableError for the failed operation op. Errors built by
+// nesting calls to E accumulate their operations, so the message reads
+// like "svc.Create: db.Insert: unique violation".
+//
+// If kind is Other, the kind of err is used instead. Any args are
+// formatted with fmt.Sprint and prepended to the message of err; if err is
+// nil they become the message. When err already carries a stack, that
+// stack is kept rather than capturing a new one.
+func E(op Op, kind Kind, err error, args ...interface{}) *StackableError {
+	return newE(op, kind, err, args, 1)
+}
+
+// ESkip is like E, but the stack leaves out the given number of frames
+// above the caller, for helpers that build errors on behalf of their own
+// callers.
+func ESkip(skip int, op Op, kind Kind, err error, args ...interface{}) *StackableError {
+	return newE(op, kind, err, args, 1+skip)
+}
+
+func newE(op Op, kind Kind, err error, args []interface{}, skip int) *StackableError {
+	if kind == Other {
+		kind = KindOf(err)
+	}
+
+	cause := err
+	if len(args) > 0 {
+		msg := fmt.Sprint(args...)
+		if err == nil {
+			cause = errors.New(msg)
+		} else {
+			cause = fmt.Errorf("%s: %w", msg, err)
+		}
+	} else if err == nil {
+		cause = errors.New(kind.String())
+	}
+
+	var inner *StackableError
+	if !errors.As(err, &inner) {
+		e := newStackableError(cause, 1+skip)
+		e.Op = op
+		e.Kind = kind
+		return created(e)
+	}
+
+	cause, depth := capDepth(cause)
+	e := &StackableError{
+		Err:    cause,
+		Op:     op,
+		Kind:   kind,
+		stack:  inner.stack,
+		frames: inner.frames,
+		depth:  depth,
+	}
+	return created(e)
+}
+
+// KindOf returns the first Kind other than Other found in the chain of
+// err, or Other if there is none.
+func KindOf(err error) Kind {
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if e, ok := err.(*StackableError); ok && e.Kind != Other {
+			return e.Kind
+		}
+	}
+	return Other
+}
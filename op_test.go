@@ -0,0 +1,85 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestE(t *testing.T) {
+	base := errors.New("unique violation")
+	inner := E("db.Insert", Exist, base)
+
+	cases := []struct {
+		name    string
+		err     *StackableError
+		message string
+		kind    Kind
+		wraps   bool // whether base is in the chain
+	}{
+		{"no cause", E("svc.Get", NotExist, nil), "svc.Get: item does not exist", NotExist, false},
+		{"args only", E("svc.Get", Invalid, nil, "id ", 7, " is negative"), "svc.Get: id 7 is negative", Invalid, false},
+		{"cause", inner, "db.Insert: unique violation", Exist, true},
+		{"nested", E("svc.Create", Other, inner), "svc.Create: db.Insert: unique violation", Exist, true},
+		{"nested with args", E("svc.Create", Other, inner, "user 1"), "svc.Create: user 1: db.Insert: unique violation", Exist, true},
+		{"kind overridden", E("svc.Create", Internal, inner), "svc.Create: db.Insert: unique violation", Internal, true},
+		{"through fmt.Errorf", E("svc.Create", Other, fmt.Errorf("tx: %w", inner)), "svc.Create: tx: db.Insert: unique violation", Exist, true},
+		{"no op", E("", IO, base), "unique violation", IO, true},
+	}
+	for _, c := range cases {
+		if c.err.Error() != c.message {
+			t.Errorf("%s: expected %q, got %q", c.name, c.message, c.err.Error())
+		}
+		if c.err.Kind != c.kind || KindOf(c.err) != c.kind {
+			t.Errorf("%s: expected kind %v, got %v", c.name, c.kind, c.err.Kind)
+		}
+		if errors.Is(c.err, base) != c.wraps {
+			t.Errorf("%s: expected errors.Is to report %v", c.name, c.wraps)
+		}
+	}
+}
+
+func TestENestedKeepsStack(t *testing.T) {
+	requireStacks(t)
+	inner := E("db.Insert", Exist, nil)
+	outer := E("svc.Create", Other, inner)
+	if &outer.StackFrames()[0] != &inner.StackFrames()[0] {
+		t.Error("expected the nested error to share the stack of its cause")
+	}
+}
+
+func TestKindNames(t *testing.T) {
+	for _, kind := range Kinds() {
+		name := kind.Name()
+		if name == "" {
+			t.Errorf("%v: expected a name", kind)
+		}
+		if parsed, ok := ParseKind(name); !ok || parsed != kind {
+			t.Errorf("%s: expected ParseKind to give %v back, got %v", name, kind, parsed)
+		}
+	}
+	if Kind(200).Name() != "" || Kind(200).String() != "unknown error kind" {
+		t.Error("expected an undeclared kind to have no name")
+	}
+	if _, ok := ParseKind("Missing"); ok {
+		t.Error("expected ParseKind to reject an unknown name")
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{"nil", nil, Other},
+		{"plain", errors.New("x"), Other},
+		{"outermost wins", E("a", Permission, E("b", NotExist, nil)), Permission},
+		{"Other is skipped", fmt.Errorf("x: %w", Wrap(E("b", NotExist, nil))), NotExist},
+	}
+	for _, c := range cases {
+		if actual := KindOf(c.err); actual != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
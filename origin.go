@@ -0,0 +1,75 @@
+package errgo
+
+import "strings"
+
+const errgoPackage = "github.com/freemish/errgo"
+
+// Origin returns the first application frame of the innermost stack in
+// the chain of err, skipping frames of errgo itself and of the standard
+// library, so logs and metrics can be labelled with where an error
+// happened. Packages whose import path has no dot in its first element,
+// other than main, are taken to be the standard library. The zero
+// StackFrame is returned if there is no such frame.
+func Origin(err error) StackFrame {
+	for _, frame := range innermostFrames(err) {
+		if isApplicationFrame(frame) {
+			return frame
+		}
+	}
+	return StackFrame{}
+}
+
+// Signature identifies err by the functions of the first n application
+// frames of its innermost stack, as in Origin, in the form
+// "pkg.Func < pkg.Caller". Unlike Fingerprint it ignores the error's type
+// and code, so it groups the different errors produced by the same bug.
+// It returns "" if err has no application frames.
+func Signature(err error, n int) string {
+	var names []string
+	for _, frame := range innermostFrames(err) {
+		if len(names) == n {
+			break
+		}
+		if isApplicationFrame(frame) {
+			names = append(names, frame.Package+"."+frame.FunctionName)
+		}
+	}
+	return strings.Join(names, " < ")
+}
+
+// innermostFrames returns the frames of the innermost non-empty stack in
+// the chain of err.
+func innermostFrames(err error) []StackFrame {
+	var frames []StackFrame
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if e, ok := err.(*StackableError); ok {
+			if f := e.StackFrames(); len(f) > 0 {
+				frames = f
+			}
+		}
+	}
+	return frames
+}
+
+func isApplicationFrame(frame StackFrame) bool {
+	return !isErrgoPackage(frame.Package) && !isStdlibPackage(frame.Package)
+}
+
+func isErrgoPackage(pkg string) bool {
+	return pkg == errgoPackage || strings.HasPrefix(pkg, errgoPackage+"/")
+}
+
+func isStdlibPackage(pkg string) bool {
+	switch pkg {
+	case "":
+		return true
+	case "main":
+		return false
+	}
+	first := pkg
+	if i := strings.Index(pkg, "/"); i >= 0 {
+		first = pkg[:i]
+	}
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,69 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsApplicationFrame(t *testing.T) {
+	cases := []struct {
+		pkg      string
+		expected bool
+	}{
+		{"", false},
+		{"main", true},
+		{"net/http", false},
+		{"runtime", false},
+		{"github.com/freemish/errgo", false},
+		{"github.com/freemish/errgo/errgohttp", false},
+		{"github.com/freemish/errgoish", true},
+		{"example.com/app/store", true},
+	}
+	for _, c := range cases {
+		if actual := isApplicationFrame(StackFrame{Package: c.pkg}); actual != c.expected {
+			t.Errorf("%q: expected %v, got %v", c.pkg, c.expected, actual)
+		}
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	frames := []StackFrame{
+		{Package: "github.com/freemish/errgo", FunctionName: "Wrap"},
+		{Package: "database/sql", FunctionName: "(*DB).Query"},
+		{Package: "example.com/app/store", FunctionName: "(*Users).Get", LineNumber: 12},
+		{Package: "example.com/app/api", FunctionName: "getUser"},
+		{Package: "main", FunctionName: "main"},
+		{Package: "runtime", FunctionName: "main"},
+	}
+	inner := WrapFrames(errors.New("boom"), frames)
+
+	cases := []struct {
+		name      string
+		err       error
+		origin    string
+		signature string
+	}{
+		{"plain", errors.New("boom"), "", ""},
+		{"frames", inner, "(*Users).Get", "example.com/app/store.(*Users).Get < example.com/app/api.getUser"},
+		{"innermost stack", Wrap(fmt.Errorf("load: %w", inner)), "(*Users).Get", "example.com/app/store.(*Users).Get < example.com/app/api.getUser"},
+		{
+			"no application frames",
+			WrapFrames(errors.New("boom"), []StackFrame{{Package: "runtime", FunctionName: "goexit"}}),
+			"",
+			"",
+		},
+	}
+	for _, c := range cases {
+		if actual := Origin(c.err); actual.FunctionName != c.origin {
+			t.Errorf("%s: expected origin %q, got %q", c.name, c.origin, actual.FunctionName)
+		}
+		if actual := Signature(c.err, 2); actual != c.signature {
+			t.Errorf("%s: expected signature %q, got %q", c.name, c.signature, actual)
+		}
+	}
+
+	if actual := Signature(inner, 5); actual != "example.com/app/store.(*Users).Get < example.com/app/api.getUser < main.main" {
+		t.Errorf("expected every application frame, got %q", actual)
+	}
+}
@@ -0,0 +1,39 @@
+package errgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PanicTrace formats the error the way the Go runtime prints an uncaught
+// panic, so tools that parse panic output, including ParsePanic, accept
+// it unchanged:
+//
+//	panic: message
+//
+//	goroutine 1 [running]:
+//	main.main()
+//		/home/user/app/main.go:10 +0x1d
+//
+// The goroutine that created the error is not recorded, so it is always
+// reported as goroutine 1. Multi-line messages are joined into one line.
+func (err *StackableError) PanicTrace() string {
+	buf := bytes.Buffer{}
+	msg := strings.Replace(err.Error(), "\n", " ", -1)
+	fmt.Fprintf(&buf, "panic: %s\n\ngoroutine 1 [running]:\n", msg)
+
+	for _, frame := range err.StackFrames() {
+		name := frame.FunctionName
+		if frame.Package != "" {
+			name = frame.Package + "." + name
+		}
+		fmt.Fprintf(&buf, "%s(...)\n\t%s:%d", name, frame.File, frame.LineNumber)
+		if fn := frame.Func(); fn != nil && frame.Caller > fn.Entry() {
+			fmt.Fprintf(&buf, " +0x%x", frame.Caller-fn.Entry())
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.String()
+}
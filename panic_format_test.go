@@ -0,0 +1,57 @@
+package errgo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPanicTrace(t *testing.T) {
+	frames := []StackFrame{
+		{File: "/app/store/users.go", LineNumber: 12, Package: "example.com/app/store", FunctionName: "(*Users).Get"},
+		{File: "/app/main.go", LineNumber: 7, Package: "main", FunctionName: "main"},
+	}
+
+	cases := []struct {
+		name     string
+		err      *StackableError
+		expected string
+	}{
+		{
+			"frames",
+			WrapFrames(errors.New("boom"), frames),
+			"panic: boom\n\ngoroutine 1 [running]:\n" +
+				"example.com/app/store.(*Users).Get(...)\n\t/app/store/users.go:12\n" +
+				"main.main(...)\n\t/app/main.go:7\n",
+		},
+		{"multi-line message", WrapFrames(errors.New("a\nb"), nil), "panic: a b\n\ngoroutine 1 [running]:\n"},
+	}
+	for _, c := range cases {
+		if actual := c.err.PanicTrace(); actual != c.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestPanicTraceParses(t *testing.T) {
+	requireStacks(t)
+
+	err := Wrap(errors.New("boom"))
+	parsed, parseErr := ParsePanic(err.PanicTrace())
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if parsed.Error() != "boom" {
+		t.Errorf("expected the message to be kept, got %q", parsed.Error())
+	}
+	expected, actual := err.StackFrames(), parsed.StackFrames()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d frames, got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		e, a := expected[i], actual[i]
+		if a.File != e.File || a.LineNumber != e.LineNumber || a.Package != e.Package || a.FunctionName != e.FunctionName {
+			t.Errorf("frame %d: expected %+v, got %+v", i, e, a)
+		}
+	}
+}
@@ -69,7 +69,7 @@ func ParsePanic(text string) (*StackableError, error) {
 	}
 
 	if state == "done" || state == "parsing" {
-		return &StackableError{Err: uncaughtPanic{message}, frames: stack}, nil
+		return &StackableError{Err: uncaughtPanic{message}, frames: newFrameCache(stack)}, nil
 	}
 	return nil, fmt.Errorf("could not parse panic: %v", text)
 }
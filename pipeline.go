@@ -0,0 +1,61 @@
+package errgo
+
+import (
+	"context"
+	"sync"
+)
+
+var processors struct {
+	sync.RWMutex
+	fns []func(*StackableError) *StackableError
+}
+
+// Use installs a processor that every new error passes through, in the
+// order processors were installed. A processor may modify the error or
+// return a different one, e.g. to redact messages, normalize codes or add
+// fields; returning nil keeps the error unchanged. Processors run once
+// the constructor has filled in the error, so codes and kinds are set, and
+// on the reporting path for errors that did not pass through them when
+// created (see Processed).
+func Use(fn func(*StackableError) *StackableError) {
+	processors.Lock()
+	defer processors.Unlock()
+	processors.fns = append(processors.fns, fn)
+}
+
+func process(err *StackableError) *StackableError {
+	processors.RLock()
+	fns := processors.fns
+	processors.RUnlock()
+
+	for _, fn := range fns {
+		if e := fn(err); e != nil {
+			err = e
+		}
+	}
+	err.processed = true
+	return err
+}
+
+// Processed returns a Reporter that runs the processors installed with Use
+// on errors before passing them on to r, for errors that did not pass
+// through them when created: those from other packages, which are wrapped
+// in a StackableError without a stack first, and StackableErrors built
+// as literals rather than by a constructor. Other errors are passed on
+// unchanged.
+func Processed(r Reporter) Reporter {
+	return ReporterFunc(func(ctx context.Context, err error) {
+		if err == nil {
+			r.Report(ctx, err)
+			return
+		}
+		e, ok := err.(*StackableError)
+		if !ok {
+			e = &StackableError{Err: err, frames: newFrameCache([]StackFrame{}), timestamp: now()}
+		} else if e.processed {
+			r.Report(ctx, err)
+			return
+		}
+		r.Report(ctx, process(e))
+	})
+}
@@ -0,0 +1,72 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The processor tags errors whose message mentions "pipeline_test", so it
+// leaves the errors of other tests alone.
+func init() {
+	Use(func(err *StackableError) *StackableError {
+		if !strings.Contains(err.Error(), "pipeline_test") {
+			return nil
+		}
+		if n, ok := err.Field("processed"); ok {
+			return err.WithField("processed", n.(int)+1)
+		}
+		return err.WithField("processed", 1)
+	})
+}
+
+func TestUse(t *testing.T) {
+	for name, err := range map[string]*StackableError{
+		"Wrap": Wrap(errors.New("pipeline_test")),
+		"E":    E("op", Invalid, nil, "pipeline_test"),
+	} {
+		if n, _ := err.Field("processed"); n != 1 {
+			t.Errorf("%s: expected the processor to run once, got %v", name, n)
+		}
+	}
+}
+
+func TestProcessed(t *testing.T) {
+	var reported []error
+	r := Processed(ReporterFunc(func(ctx context.Context, err error) {
+		reported = append(reported, err)
+	}))
+
+	created := Wrap(errors.New("pipeline_test created"))
+	plain := fmt.Errorf("pipeline_test plain")
+	literal := &StackableError{Err: errors.New("pipeline_test literal"), frames: newFrameCache(nil)}
+	cases := []struct {
+		name string
+		err  error
+		runs interface{}
+	}{
+		{"created", created, 1},
+		{"plain", plain, 1},
+		{"literal", literal, 1},
+		{"unrelated", errors.New("other"), nil},
+	}
+	for _, c := range cases {
+		reported = nil
+		r.Report(context.Background(), c.err)
+		if len(reported) != 1 {
+			t.Fatalf("%s: expected one report, got %v", c.name, reported)
+		}
+		var n interface{}
+		if e, ok := reported[0].(*StackableError); ok {
+			n, _ = e.Field("processed")
+		}
+		if n != c.runs {
+			t.Errorf("%s: expected the processor to have run %v times, got %v", c.name, c.runs, n)
+		}
+		if !errors.Is(reported[0], c.err) {
+			t.Errorf("%s: expected the report to wrap the error", c.name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package errgo
+
+import (
+	"fmt"
+	"sync"
+)
+
+var errorPool = sync.Pool{
+	New: func() interface{} { return &StackableError{} },
+}
+
+// Acquire is like Wrap, but takes the StackableError and its stack buffer
+// from a pool, for hot paths that create many short-lived errors. A value
+// that is already a *StackableError is returned unchanged.
+//
+// The caller owns an acquired error until it passes it to Release. After
+// that, neither the error nor anything obtained from it, such as its
+// Prefixes, Callers or StackFrames, may be used again. Errors that escape
+// the caller's control, for example by being returned from an API,
+// stored, or reported asynchronously, must never be released.
+func Acquire(e interface{}) *StackableError {
+	var cause error
+	switch e := e.(type) {
+	case *StackableError:
+		return e
+	case error:
+		cause = e
+		if IsTypedNil(e) {
+			cause = typedNilError{e}
+		}
+	default:
+		cause = fmt.Errorf("%v", e)
+	}
+
+	err := errorPool.Get().(*StackableError)
+	stack := err.stack[:0]
+	if cap(stack) < MaxStackDepth {
+		stack = make([]uintptr, MaxStackDepth)
+	}
+	stack = stack[:MaxStackDepth]
+	err.stack = stack[:callersInto(1, stack)]
+	err.Err = cause
+	err.frames = newFrameCache(nil)
+	err.pooled = true
+
+	if processed := created(err); processed != err {
+		err.pooled = false
+		return processed
+	}
+	return err
+}
+
+// Release returns an error obtained from Acquire to the pool. It does
+// nothing for errors that did not come from Acquire, or that have been
+// frozen. See Acquire for the rules on when an error may be released.
+func Release(err *StackableError) {
+	if err == nil || !err.pooled || err.frozen {
+		return
+	}
+	*err = StackableError{
+		Prefixes: err.Prefixes[:0],
+		stack:    err.stack[:0],
+	}
+	errorPool.Put(err)
+}
@@ -0,0 +1,38 @@
+package errgo
+
+import "testing"
+
+func TestPoolReuseDoesNotLeak(t *testing.T) {
+	requireStacks(t)
+	for i := 0; i < 100; i++ {
+		err := Acquire("first")
+		if err.Code != "" || len(err.Prefixes) != 0 || len(err.Fields()) != 0 || len(err.Details()) != 0 || err.Kind != Other {
+			t.Fatalf("acquired error carries state from a released one: %#v", err)
+		}
+		if err.Error() != "first" {
+			t.Fatalf("expected message %q, got %q", "first", err.Error())
+		}
+		if len(err.Callers()) == 0 {
+			t.Fatal("acquired error has no stack")
+		}
+
+		err = WrapPrefix(err, "prefix").WithField("id", i).AddDetail("detail")
+		err.Code = "E1"
+		err.Kind = NotExist
+		Release(err)
+	}
+}
+
+func TestReleaseIgnoresOtherErrors(t *testing.T) {
+	err := Wrap("not pooled")
+	Release(err)
+	if err.Error() != "not pooled" {
+		t.Errorf("Release changed an error that was not acquired: %q", err.Error())
+	}
+
+	frozen := Acquire("frozen").Freeze()
+	Release(frozen)
+	if frozen.Error() != "frozen" {
+		t.Errorf("Release changed a frozen error: %q", frozen.Error())
+	}
+}
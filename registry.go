@@ -0,0 +1,85 @@
+package errgo
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// A Definition describes an error code: its default message, its Kind,
+// and how it maps to HTTP and gRPC status codes.
+type Definition struct {
+	Code       string
+	Message    string
+	Kind       Kind
+	HTTPStatus int
+	GRPCCode   int    // numeric google.rpc.Code
+	Package    string // import path of the package that registered the code
+}
+
+var registry = struct {
+	sync.RWMutex
+	defs map[string]Definition
+}{defs: map[string]Definition{}}
+
+// Register adds an error code to the registry, typically from an init
+// function, and returns its definition. The Package field is filled in
+// with the caller's package if it is empty. Register panics if the code is
+// empty or already registered.
+func Register(def Definition) Definition {
+	if def.Code == "" {
+		panic("errgo: Register called with an empty code")
+	}
+	if def.Package == "" {
+		if pc, _, _, ok := runtime.Caller(1); ok {
+			def.Package = NewStackFrame(pc).Package
+		}
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, dup := registry.defs[def.Code]; dup {
+		panic("errgo: Register called twice for code " + def.Code)
+	}
+	registry.defs[def.Code] = def
+	return def
+}
+
+// Lookup returns the definition registered for code.
+func Lookup(code string) (Definition, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	def, ok := registry.defs[code]
+	return def, ok
+}
+
+// Definitions returns every registered definition, sorted by code.
+func Definitions() []Definition {
+	registry.RLock()
+	defer registry.RUnlock()
+	defs := make([]Definition, 0, len(registry.defs))
+	for _, def := range registry.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Code < defs[j].Code })
+	return defs
+}
+
+// NewCode makes a StackableError with the given registered code, taking
+// its Kind from the code's definition. If err is nil, the definition's
+// message is used as the error. Codes that were never registered are
+// still set, with the code itself as the default message.
+func NewCode(code string, err error) *StackableError {
+	def, ok := Lookup(code)
+	if !ok {
+		def = Definition{Code: code, Message: code}
+	}
+	if err == nil {
+		err = errors.New(def.Message)
+	}
+	e := newStackableError(err, 1)
+	e.Code = code
+	e.Kind = def.Kind
+	return created(e)
+}
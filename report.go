@@ -0,0 +1,122 @@
+package errgo
+
+import (
+	"context"
+	"regexp"
+	"sync"
+)
+
+// A Reporter sends errors somewhere they can be looked at, such as an
+// error tracker or a log file.
+type Reporter interface {
+	Report(ctx context.Context, err error)
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(ctx context.Context, err error)
+
+// Report calls f(ctx, err).
+func (f ReporterFunc) Report(ctx context.Context, err error) {
+	f(ctx, err)
+}
+
+// A Filter reports whether an error is expected noise that should not be
+// reported.
+type Filter func(err error) bool
+
+var ignored struct {
+	sync.RWMutex
+	filters []Filter
+}
+
+// Ignore adds filters to the global ignore list consulted by Ignored and
+// by every Reporter returned from Filtered.
+func Ignore(filters ...Filter) {
+	ignored.Lock()
+	defer ignored.Unlock()
+	ignored.filters = append(ignored.filters, filters...)
+}
+
+// Ignored reports whether err matches any filter of the global ignore
+// list. A nil error is always ignored.
+func Ignored(err error) bool {
+	if err == nil {
+		return true
+	}
+	ignored.RLock()
+	defer ignored.RUnlock()
+	return matchAny(ignored.filters, err)
+}
+
+// Filtered returns a Reporter that passes errors on to r unless they are
+// Ignored or match one of the given filters.
+func Filtered(r Reporter, filters ...Filter) Reporter {
+	return ReporterFunc(func(ctx context.Context, err error) {
+		if Ignored(err) || matchAny(filters, err) {
+			return
+		}
+		r.Report(ctx, err)
+	})
+}
+
+func matchAny(filters []Filter, err error) bool {
+	for _, filter := range filters {
+		if filter(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoreKinds matches errors whose KindOf is one of kinds.
+func IgnoreKinds(kinds ...Kind) Filter {
+	return func(err error) bool {
+		kind := KindOf(err)
+		for _, k := range kinds {
+			if kind == k {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IgnoreCodes matches errors with one of the given codes anywhere in their
+// chain.
+func IgnoreCodes(codes ...string) Filter {
+	return func(err error) bool {
+		errs, _ := walkChain(err)
+		for _, err := range errs {
+			e, ok := err.(*StackableError)
+			if !ok || e.Code == "" {
+				continue
+			}
+			for _, code := range codes {
+				if e.Code == code {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// IgnoreErrors matches errors for which Is reports a match with one of
+// the targets, e.g. context.Canceled.
+func IgnoreErrors(targets ...error) Filter {
+	return func(err error) bool {
+		for _, target := range targets {
+			if Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IgnoreMessages matches errors whose message matches re.
+func IgnoreMessages(re *regexp.Regexp) Filter {
+	return func(err error) bool {
+		return re.MatchString(err.Error())
+	}
+}
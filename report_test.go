@@ -0,0 +1,66 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	coded := E("quota.Check", Invalid, nil)
+	coded.Code = "quota_exceeded"
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		err      error
+		expected bool
+	}{
+		{"kind", IgnoreKinds(NotExist), E("db.Get", NotExist, nil), true},
+		{"other kind", IgnoreKinds(NotExist), E("db.Get", IO, nil), false},
+		{"code", IgnoreCodes("quota_exceeded"), fmt.Errorf("api: %w", coded), true},
+		{"other code", IgnoreCodes("rate_limited"), coded, false},
+		{"error", IgnoreErrors(context.Canceled), Wrap(fmt.Errorf("query: %w", context.Canceled)), true},
+		{"joined error", IgnoreErrors(context.Canceled), errors.Join(errors.New("a"), context.Canceled), true},
+		{"multi-error", IgnoreErrors(context.Canceled), Append(errors.New("a"), context.Canceled), true},
+		{"other error", IgnoreErrors(context.Canceled), errors.New("canceled"), false},
+		{"message", IgnoreMessages(regexp.MustCompile(`^broken pipe`)), errors.New("broken pipe"), true},
+		{"other message", IgnoreMessages(regexp.MustCompile(`^broken pipe`)), errors.New("reset"), false},
+	}
+	for _, tt := range tests {
+		if matched := tt.filter(tt.err); matched != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, matched)
+		}
+	}
+}
+
+// errIgnoredForTest is added to the global ignore list by the tests.
+var errIgnoredForTest = errors.New("ignored for test")
+
+func TestIgnored(t *testing.T) {
+	Ignore(IgnoreErrors(errIgnoredForTest))
+
+	var reported []error
+	r := Filtered(ReporterFunc(func(ctx context.Context, err error) {
+		reported = append(reported, err)
+	}), IgnoreKinds(NotExist))
+
+	r.Report(context.Background(), nil)
+	r.Report(context.Background(), Wrap(errIgnoredForTest))
+	r.Report(context.Background(), E("db.Get", NotExist, nil))
+	r.Report(context.Background(), errors.New("reported"))
+	if len(reported) != 1 || reported[0].Error() != "reported" {
+		t.Errorf("expected only the unignored error to be reported, got %v", reported)
+	}
+
+	a := NewAggregator(nil)
+	if state := a.Add(fmt.Errorf("retry: %w", errIgnoredForTest)); state.Count != 0 {
+		t.Errorf("expected the ignored error not to be counted, got %+v", state)
+	}
+	a.Report(context.Background(), errIgnoredForTest)
+	if groups := a.Aggregates(); len(groups) != 0 {
+		t.Errorf("expected no groups, got %+v", groups)
+	}
+}
@@ -0,0 +1,185 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Retry spaces out attempts. Zero fields take
+// the defaults noted below.
+type RetryPolicy struct {
+	MaxAttempts    int           // default 3
+	InitialBackoff time.Duration // default 100ms
+	MaxBackoff     time.Duration // default 10s
+	Multiplier     float64       // default 2
+	Jitter         bool          // randomize each backoff by up to ±50%
+}
+
+// An Attempt is one call made by Retry.
+type Attempt struct {
+	Number   int
+	Start    time.Time
+	Duration time.Duration
+	Err      *StackableError
+}
+
+// RetryError is the error returned by Retry when every attempt failed. It
+// keeps the error, with its stack, and the timing of each attempt.
+type RetryError struct {
+	Attempts []Attempt
+	Cause    error // set if retrying stopped because the context ended
+}
+
+// Error describes the number of attempts and the last error.
+func (e *RetryError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed after %d attempts", len(e.Attempts))
+	if e.Cause != nil {
+		fmt.Fprintf(&b, " (%s)", e.Cause.Error())
+	}
+	if n := len(e.Attempts); n > 0 {
+		b.WriteString(": " + e.Attempts[n-1].Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of all attempts, and the context error if
+// there is one, for use with errors.Is and errors.As.
+func (e *RetryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	for _, attempt := range e.Attempts {
+		errs = append(errs, attempt.Err)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+	return errs
+}
+
+// Retry calls fn until it succeeds, the policy runs out of attempts, the
+// error is not retryable according to IsRetryable, or ctx ends. Between
+// attempts it waits for the policy's backoff, or for RetryAfter(err) if
+// the error asks for a longer wait. If no attempt succeeds, it returns a
+// StackableError holding a *RetryError with every attempt, whose kind is
+// that of the last attempt.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	policy = policy.withDefaults()
+	result := &RetryError{}
+	backoff := policy.InitialBackoff
+
+	for n := 1; ; n++ {
+		start := now()
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		result.Attempts = append(result.Attempts, Attempt{
+			Number:   n,
+			Start:    start,
+			Duration: now().Sub(start),
+			Err:      wrap(err, 1),
+		})
+
+		if n >= policy.MaxAttempts || !IsRetryable(err) {
+			break
+		}
+
+		wait := policy.jitter(backoff)
+		if after, ok := RetryAfter(err); ok && after > wait {
+			wait = after
+		}
+		if ctxErr := sleep(ctx, wait); ctxErr != nil {
+			result.Cause = ctxErr
+			break
+		}
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+
+	e := newStackableError(result, 1)
+	e.Kind = KindOf(result.Attempts[len(result.Attempts)-1].Err)
+	return created(e)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 10 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	return p
+}
+
+func (p RetryPolicy) jitter(d time.Duration) time.Duration {
+	if !p.Jitter || d <= 0 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsRetryable reports whether the operation that failed with err may
+// succeed if tried again. The first error in the chain with a
+// Retryable() bool method decides. Failing that, context cancellation is
+// not retryable, even though context.DeadlineExceeded reports itself as
+// temporary, and then the first error with a Temporary() bool method
+// decides; otherwise errors of kind Invalid, Permission, Exist and
+// NotExist are not retryable, and everything else is.
+func IsRetryable(err error) bool {
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if r, ok := err.(interface{ Retryable() bool }); ok {
+			return r.Retryable()
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	for _, err := range errs {
+		if t, ok := err.(interface{ Temporary() bool }); ok {
+			return t.Temporary()
+		}
+	}
+
+	switch KindOf(err) {
+	case Invalid, Permission, Exist, NotExist:
+		return false
+	}
+	return true
+}
+
+// RetryAfter returns the delay requested by an error in the chain of err
+// with a RetryAfter() time.Duration method, such as one built from an
+// HTTP Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if r, ok := err.(interface{ RetryAfter() time.Duration }); ok {
+			return r.RetryAfter(), true
+		}
+	}
+	return 0, false
+}
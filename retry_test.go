@@ -0,0 +1,156 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type temporaryError struct{ temporary bool }
+
+func (e temporaryError) Error() string   { return "temporary" }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+type retryableError struct{ retryable bool }
+
+func (e retryableError) Error() string   { return "retryable" }
+func (e retryableError) Retryable() bool { return e.retryable }
+
+type retryAfterError time.Duration
+
+func (e retryAfterError) Error() string             { return "slow down" }
+func (e retryAfterError) RetryAfter() time.Duration { return time.Duration(e) }
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"plain", errors.New("boom"), true},
+		{"Transient", E("op", Transient, nil), true},
+		{"Invalid", E("op", Invalid, nil), false},
+		{"NotExist", fmt.Errorf("load: %w", E("op", NotExist, nil)), false},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), false},
+		{"deadline", context.DeadlineExceeded, false},
+		{"temporary", temporaryError{true}, true},
+		{"not temporary", E("op", Transient, temporaryError{false}), false},
+		{"Retryable decides", E("op", Invalid, retryableError{true}), true},
+		{"not Retryable", retryableError{false}, false},
+	}
+	for _, c := range cases {
+		if actual := IsRetryable(c.err); actual != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	failures := func(errs ...error) func(context.Context) error {
+		return func(context.Context) error {
+			if len(errs) == 0 {
+				return nil
+			}
+			err := errs[0]
+			errs = errs[1:]
+			return err
+		}
+	}
+	boom := errors.New("boom")
+	invalid := E("op", Invalid, nil, "bad input")
+
+	cases := []struct {
+		name     string
+		fn       func(context.Context) error
+		attempts int // 0 if Retry succeeds
+		kind     Kind
+		message  string
+	}{
+		{"success", failures(), 0, Other, ""},
+		{"success after failures", failures(boom, boom), 0, Other, ""},
+		{"every attempt fails", failures(boom, boom, E("op", IO, nil, "down")), 3, IO, "failed after 3 attempts: op: down"},
+		{"not retryable", failures(boom, invalid), 2, Invalid, "failed after 2 attempts: op: bad input"},
+	}
+	for _, c := range cases {
+		err := Retry(context.Background(), policy, c.fn)
+		if c.attempts == 0 {
+			if err != nil {
+				t.Errorf("%s: expected success, got %v", c.name, err)
+			}
+			continue
+		}
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) {
+			t.Fatalf("%s: expected a RetryError, got %v", c.name, err)
+		}
+		if len(retryErr.Attempts) != c.attempts {
+			t.Errorf("%s: expected %d attempts, got %d", c.name, c.attempts, len(retryErr.Attempts))
+		}
+		for i, attempt := range retryErr.Attempts {
+			if attempt.Number != i+1 || attempt.Err == nil {
+				t.Errorf("%s: unexpected attempt %d: %+v", c.name, i, attempt)
+			}
+		}
+		if KindOf(err) != c.kind {
+			t.Errorf("%s: expected kind %v, got %v", c.name, c.kind, KindOf(err))
+		}
+		if err.Error() != c.message {
+			t.Errorf("%s: expected %q, got %q", c.name, c.message, err.Error())
+		}
+		if !errors.Is(err, boom) {
+			t.Errorf("%s: expected the attempts to be unwrapped", c.name)
+		}
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Retry(ctx, RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}, func(context.Context) error {
+		cancel()
+		return errors.New("boom")
+	})
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || len(retryErr.Attempts) != 1 || retryErr.Cause != context.Canceled {
+		t.Fatalf("expected one attempt stopped by the context, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("expected the context error to be unwrapped")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var starts []time.Time
+	err := Retry(context.Background(), RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}, func(context.Context) error {
+		starts = append(starts, time.Now())
+		return retryAfterError(20 * time.Millisecond)
+	})
+	if err == nil || len(starts) != 2 {
+		t.Fatalf("expected two failed attempts, got %v", err)
+	}
+	if wait := starts[1].Sub(starts[0]); wait < 20*time.Millisecond {
+		t.Errorf("expected to wait for RetryAfter, waited %v", wait)
+	}
+	if d, ok := RetryAfter(fmt.Errorf("x: %w", retryAfterError(time.Second))); !ok || d != time.Second {
+		t.Errorf("expected RetryAfter to find the wrapped delay, got %v %v", d, ok)
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	p := RetryPolicy{Multiplier: 0.5}.withDefaults()
+	expected := RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second, Multiplier: 2}
+	if p != expected {
+		t.Errorf("expected %+v, got %+v", expected, p)
+	}
+
+	p = RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: true}
+	for i := 0; i < 100; i++ {
+		if d := p.jitter(p.InitialBackoff); d < 50*time.Millisecond || d >= 150*time.Millisecond {
+			t.Fatalf("jittered backoff %v out of range", d)
+		}
+	}
+}
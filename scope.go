@@ -0,0 +1,55 @@
+package errgo
+
+import "context"
+
+type scopeKey struct{}
+
+// scope is one operation in a chain of nested scopes.
+type scope struct {
+	name   string
+	parent *scope
+}
+
+// PushScope returns a context in which name, such as "load user", is the
+// innermost of the active operation scopes. Errors made by WrapContext
+// with the context get the names of the active scopes as prefixes, so
+// their message reads like "handle request: load user: not found".
+func PushScope(ctx context.Context, name string) context.Context {
+	parent, _ := ctx.Value(scopeKey{}).(*scope)
+	return context.WithValue(ctx, scopeKey{}, &scope{name: name, parent: parent})
+}
+
+// PopScope returns a context in which the innermost scope of ctx is no
+// longer active.
+func PopScope(ctx context.Context) context.Context {
+	s, _ := ctx.Value(scopeKey{}).(*scope)
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, scopeKey{}, s.parent)
+}
+
+// Scopes returns the names of the active scopes of ctx, outermost first.
+func Scopes(ctx context.Context) []string {
+	var names []string
+	for s, _ := ctx.Value(scopeKey{}).(*scope); s != nil; s = s.parent {
+		names = append([]string{s.name}, names...)
+	}
+	return names
+}
+
+// newScopes returns the scopes of ctx that have not been applied to err
+// yet, innermost first. Scopes are applied when an error passes through
+// WrapContext, and again as it is wrapped further out, so only the scopes
+// outside the last applied one are new.
+func newScopes(ctx context.Context, err *StackableError) []*scope {
+	applied := map[*scope]bool{}
+	for s := err.scope; s != nil; s = s.parent {
+		applied[s] = true
+	}
+	var scopes []*scope
+	for s, _ := ctx.Value(scopeKey{}).(*scope); s != nil && !applied[s]; s = s.parent {
+		scopes = append(scopes, s)
+	}
+	return scopes
+}
@@ -0,0 +1,72 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestScopes(t *testing.T) {
+	ctx := context.Background()
+	handle := PushScope(ctx, "handle request")
+	load := PushScope(handle, "load user")
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		expected []string
+	}{
+		{"none", ctx, nil},
+		{"one", handle, []string{"handle request"}},
+		{"nested", load, []string{"handle request", "load user"}},
+		{"popped", PopScope(load), []string{"handle request"}},
+		{"popped empty", PopScope(ctx), nil},
+	}
+	for _, c := range cases {
+		if actual := Scopes(c.ctx); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestWrapContextScopes(t *testing.T) {
+	base := errors.New("not found")
+	handle := PushScope(context.Background(), "handle request")
+	load := PushScope(handle, "load user")
+
+	cases := []struct {
+		name     string
+		wrap     func() error
+		expected string
+	}{
+		{"no scopes", func() error { return WrapContext(context.Background(), base) }, "not found"},
+		{"nested scopes", func() error { return WrapContext(load, base) }, "handle request: load user: not found"},
+		{
+			"wrapped again outside",
+			func() error { return WrapContext(handle, WrapContext(load, base)) },
+			"handle request: load user: not found",
+		},
+		{
+			"wrapped again in the same scope",
+			func() error { return WrapContext(load, WrapContext(load, base)) },
+			"handle request: load user: not found",
+		},
+		{
+			"frozen",
+			func() error { return WrapContext(load, Wrap(base).Freeze()) },
+			"handle request: load user: not found",
+		},
+	}
+	for _, c := range cases {
+		if actual := c.wrap().Error(); actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, actual)
+		}
+	}
+
+	frozen := Wrap(base).Freeze()
+	WrapContext(load, frozen)
+	if frozen.Error() != "not found" {
+		t.Errorf("expected the frozen error to be left alone, got %q", frozen.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Severity says how urgently an error needs attention. The zero value
+// means the severity is derived from the error's Kind.
+type Severity uint8
+
+// Severities, from least to most urgent.
+const (
+	SeverityUnset    Severity = iota
+	SeverityInfo              // Expected, e.g. caused by bad user input.
+	SeverityWarning           // Degraded but recoverable.
+	SeverityError             // Needs fixing.
+	SeverityCritical          // Needs fixing now, e.g. data corruption.
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityUnset:
+		return "unset"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// LevelCritical is the slog level used for SeverityCritical errors.
+const LevelCritical = slog.LevelError + 4
+
+// SeverityOf returns the first severity set in the chain of err. If none
+// is set, it is derived from KindOf(err): invalid input, permission and
+// existence errors are SeverityInfo, I/O and transient errors are
+// SeverityWarning and everything else is SeverityError.
+func SeverityOf(err error) Severity {
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if e, ok := err.(*StackableError); ok && e.Severity != SeverityUnset {
+			return e.Severity
+		}
+	}
+
+	switch KindOf(err) {
+	case Invalid, Permission, Exist, NotExist:
+		return SeverityInfo
+	case IO, Transient:
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+// LogLevel returns the slog level matching SeverityOf(err).
+func LogLevel(err error) slog.Level {
+	switch SeverityOf(err) {
+	case SeverityInfo:
+		return slog.LevelInfo
+	case SeverityWarning:
+		return slog.LevelWarn
+	case SeverityCritical:
+		return LevelCritical
+	}
+	return slog.LevelError
+}
+
+// SyslogSeverity returns the RFC 5424 severity matching SeverityOf(err):
+// 2 (critical), 3 (error), 4 (warning) or 6 (informational).
+func SyslogSeverity(err error) int {
+	switch SeverityOf(err) {
+	case SeverityInfo:
+		return 6
+	case SeverityWarning:
+		return 4
+	case SeverityCritical:
+		return 2
+	}
+	return 3
+}
+
+// Log logs err to logger at the level given by LogLevel, with its code
+// and kind as attributes.
+func Log(ctx context.Context, logger *slog.Logger, err error) {
+	attrs := []slog.Attr{slog.String("kind", KindOf(err).String())}
+	var e *StackableError
+	if errors.As(err, &e) && e.Code != "" {
+		attrs = append(attrs, slog.String("code", e.Code))
+	}
+	logger.LogAttrs(ctx, LogLevel(err), err.Error(), attrs...)
+}
@@ -0,0 +1,62 @@
+package errgo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSeverityOf(t *testing.T) {
+	critical := E("db.Write", Internal, nil)
+	critical.Severity = SeverityCritical
+	quiet := E("cache.Get", IO, nil)
+	quiet.Severity = SeverityInfo
+
+	cases := []struct {
+		name     string
+		err      error
+		severity Severity
+		level    slog.Level
+		syslog   int
+	}{
+		{"plain", errors.New("boom"), SeverityError, slog.LevelError, 3},
+		{"invalid", E("", Invalid, nil), SeverityInfo, slog.LevelInfo, 6},
+		{"not exist", E("", NotExist, nil), SeverityInfo, slog.LevelInfo, 6},
+		{"io", E("", IO, nil), SeverityWarning, slog.LevelWarn, 4},
+		{"transient", E("", Transient, nil), SeverityWarning, slog.LevelWarn, 4},
+		{"internal", E("", Internal, nil), SeverityError, slog.LevelError, 3},
+		{"set", critical, SeverityCritical, LevelCritical, 2},
+		{"set overrides kind", quiet, SeverityInfo, slog.LevelInfo, 6},
+		{"wrapped", fmt.Errorf("save: %w", critical), SeverityCritical, LevelCritical, 2},
+		{"outer kind", E("svc.Save", Other, critical), SeverityCritical, LevelCritical, 2},
+	}
+	for _, c := range cases {
+		if actual := SeverityOf(c.err); actual != c.severity {
+			t.Errorf("%s: expected severity %v, got %v", c.name, c.severity, actual)
+		}
+		if actual := LogLevel(c.err); actual != c.level {
+			t.Errorf("%s: expected level %v, got %v", c.name, c.level, actual)
+		}
+		if actual := SyslogSeverity(c.err); actual != c.syslog {
+			t.Errorf("%s: expected syslog severity %d, got %d", c.name, c.syslog, actual)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	err := E("users.Get", NotExist, nil)
+	err.Code = "user_missing"
+
+	buf := bytes.Buffer{}
+	Log(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)), err)
+
+	for _, s := range []string{"level=INFO", `msg="users.Get: item does not exist"`, `kind="item does not exist"`, "code=user_missing"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in %q", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package errgo
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SimilarityFrames is the number of innermost frames compared by
+// Similarity.
+var SimilarityFrames = 5
+
+// variableParts matches the parts of error messages that typically differ
+// between occurrences of the same failure: quoted strings, UUIDs, hex
+// addresses, IP addresses and numbers, including those followed by a
+// unit such as "2.5s".
+var variableParts = regexp.MustCompile(`"[^"]*"|'[^']*'|` +
+	`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b|` +
+	`\b0x[0-9a-fA-F]+\b|` +
+	`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b|` +
+	`\b\d+(\.\d+)?`)
+
+// NormalizeMessage replaces the variable parts of msg, such as IDs,
+// addresses and quoted values, with "*".
+func NormalizeMessage(msg string) string {
+	return variableParts.ReplaceAllString(msg, "*")
+}
+
+// Similarity returns a score between 0 and 1 of how alike two errors are.
+// It compares their messages with variable parts removed and, if both
+// carry a stack, the functions of their innermost frames. Errors whose
+// messages differ only in IDs or addresses and that were created at the
+// same place score 1.
+func Similarity(a, b error) float64 {
+	if a == nil || b == nil {
+		if a == b {
+			return 1
+		}
+		return 0
+	}
+	score := jaccard(strings.Fields(NormalizeMessage(a.Error())), strings.Fields(NormalizeMessage(b.Error())))
+
+	framesA, framesB := topFrames(a), topFrames(b)
+	if len(framesA) == 0 || len(framesB) == 0 {
+		return score
+	}
+	return 0.6*score + 0.4*jaccard(framesA, framesB)
+}
+
+// An ErrorCluster is a group of similar errors.
+type ErrorCluster struct {
+	Pattern string  // normalized message of the first error
+	Errors  []error // in the order they were given
+}
+
+// Cluster groups errs so that each error is in the cluster of the first
+// earlier error it has a Similarity of at least threshold with. Clusters
+// are returned largest first.
+func Cluster(errs []error, threshold float64) []ErrorCluster {
+	var clusters []ErrorCluster
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		i := 0
+		for ; i < len(clusters); i++ {
+			if Similarity(clusters[i].Errors[0], err) >= threshold {
+				break
+			}
+		}
+		if i == len(clusters) {
+			clusters = append(clusters, ErrorCluster{Pattern: NormalizeMessage(err.Error())})
+		}
+		clusters[i].Errors = append(clusters[i].Errors, err)
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Errors) > len(clusters[j].Errors)
+	})
+	return clusters
+}
+
+func topFrames(err error) []string {
+	var e *StackableError
+	if !errors.As(err, &e) || e == nil {
+		return nil
+	}
+	frames := e.StackFrames()
+	if len(frames) > SimilarityFrames {
+		frames = frames[:SimilarityFrames]
+	}
+	names := make([]string, len(frames))
+	for i, frame := range frames {
+		names[i] = frame.Package + "." + frame.FunctionName
+	}
+	return names
+}
+
+// jaccard returns the size of the intersection of a and b divided by the
+// size of their union.
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := make(map[string]int, len(a))
+	for _, s := range a {
+		set[s] |= 1
+	}
+	for _, s := range b {
+		set[s] |= 2
+	}
+	both := 0
+	for _, v := range set {
+		if v == 3 {
+			both++
+		}
+	}
+	return float64(both) / float64(len(set))
+}
@@ -0,0 +1,76 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	cases := []struct {
+		msg      string
+		expected string
+	}{
+		{`user "alice" not found`, "user * not found"},
+		{"order 1234 failed after 2.5s", "order * failed after *s"},
+		{"dial tcp 10.0.0.1:5432: refused", "dial tcp *: refused"},
+		{"row 3f2504e0-4f89-11d3-9a0c-0305e82c3301 locked at 0xc000123", "row * locked at *"},
+		{"no variable parts", "no variable parts"},
+	}
+	for _, c := range cases {
+		if actual := NormalizeMessage(c.msg); actual != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.msg, c.expected, actual)
+		}
+	}
+}
+
+func similarityTestError(id int) error {
+	return Wrap(fmt.Errorf("order %d not found", id))
+}
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     error
+		min, max float64
+	}{
+		{"nils", nil, nil, 1, 1},
+		{"nil and error", nil, errors.New("a"), 0, 0},
+		{"same message", errors.New("order 1 not found"), errors.New("order 2 not found"), 1, 1},
+		{"different messages", errors.New("disk full"), errors.New("order not found"), 0, 0},
+		{"partly alike", errors.New("order not found"), errors.New("user not found"), 0.4, 0.6},
+		{"same place", similarityTestError(1), similarityTestError(2), 1, 1},
+	}
+	for _, c := range cases {
+		if score := Similarity(c.a, c.b); score < c.min || score > c.max {
+			t.Errorf("%s: expected a score between %v and %v, got %v", c.name, c.min, c.max, score)
+		}
+	}
+}
+
+func TestSimilarityFrames(t *testing.T) {
+	requireStacks(t)
+	if score := Similarity(similarityTestError(1), Wrap(errors.New("order 1 not found"))); score < 0.6 || score >= 1 {
+		t.Errorf("expected errors made at different places to score between 0.6 and 1, got %v", score)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	errs := []error{
+		errors.New("disk full"),
+		errors.New("order 1 not found"),
+		nil,
+		errors.New("order 2 not found"),
+		errors.New("order 3 not found"),
+	}
+	clusters := Cluster(errs, 0.9)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+	if clusters[0].Pattern != "order * not found" || len(clusters[0].Errors) != 3 {
+		t.Errorf("expected the order errors first, got %+v", clusters[0])
+	}
+	if clusters[1].Pattern != "disk full" || len(clusters[1].Errors) != 1 {
+		t.Errorf("expected the disk error second, got %+v", clusters[1])
+	}
+}
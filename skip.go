@@ -0,0 +1,63 @@
+package errgo
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var skipPackages struct {
+	sync.RWMutex
+	pkgs []string
+	pcs  sync.Map // pc -> bool, whether the pc is in a skipped package
+}
+
+// SetSkipPackages drops the frames of the given packages, and of packages
+// below them, from every stack captured afterwards, e.g. for middleware
+// that shows up in every trace. Unlike a display filter, this keeps the
+// frames out of the stored stack, so serialized errors stay small.
+// Calling SetSkipPackages again replaces the list.
+func SetSkipPackages(pkgs ...string) {
+	skipPackages.Lock()
+	defer skipPackages.Unlock()
+	skipPackages.pkgs = append([]string(nil), pkgs...)
+	skipPackages.pcs = sync.Map{}
+}
+
+// dropSkipped removes the program counters of skipped packages from
+// stack, in place, and returns the number kept.
+func dropSkipped(stack []uintptr) int {
+	skipPackages.RLock()
+	defer skipPackages.RUnlock()
+	if len(skipPackages.pkgs) == 0 {
+		return len(stack)
+	}
+
+	n := 0
+	for _, pc := range stack {
+		if !skippedPC(pc) {
+			stack[n] = pc
+			n++
+		}
+	}
+	return n
+}
+
+func skippedPC(pc uintptr) bool {
+	if skip, ok := skipPackages.pcs.Load(pc); ok {
+		return skip.(bool)
+	}
+
+	skip := false
+	if fn := runtime.FuncForPC(pc - 1); fn != nil {
+		pkg, _ := packageAndName(fn)
+		for _, p := range skipPackages.pkgs {
+			if pkg == p || strings.HasPrefix(pkg, p+"/") {
+				skip = true
+				break
+			}
+		}
+	}
+	skipPackages.pcs.Store(pc, skip)
+	return skip
+}
@@ -0,0 +1,53 @@
+package errgo
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+// errorInSort makes an error from inside a sort.Slice callback, so its
+// stack has frames of package sort between those of the test.
+func errorInSort() *StackableError {
+	var err *StackableError
+	sort.Slice([]int{2, 1}, func(i, j int) bool {
+		if err == nil {
+			err = Wrap(errors.New("x"))
+		}
+		return i < j
+	})
+	return err
+}
+
+func TestSetSkipPackages(t *testing.T) {
+	requireStacks(t)
+	defer SetSkipPackages()
+
+	cases := []struct {
+		name  string
+		pkgs  []string
+		sort  bool // whether frames of package sort are kept
+		errgo bool // whether frames of this package are kept
+	}{
+		{"none", nil, true, true},
+		{"sort", []string{"sort"}, false, true},
+		{"prefix of a path element", []string{"github.com/freemish/err"}, true, true},
+		{"module", []string{"github.com/freemish"}, true, false},
+		{"several", []string{"sort", "github.com/freemish/errgo"}, false, false},
+	}
+	for _, c := range cases {
+		SetSkipPackages(c.pkgs...)
+		sortKept, errgoKept := false, false
+		for _, frame := range errorInSort().StackFrames() {
+			switch frame.Package {
+			case "sort":
+				sortKept = true
+			case "github.com/freemish/errgo":
+				errgoKept = true
+			}
+		}
+		if sortKept != c.sort || errgoKept != c.errgo {
+			t.Errorf("%s: expected sort frames %v and errgo frames %v, got %v and %v", c.name, c.sort, c.errgo, sortKept, errgoKept)
+		}
+	}
+}
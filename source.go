@@ -0,0 +1,86 @@
+package errgo
+
+import (
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SourceURLTemplate is used by StackFrame.SourceURL to link frames to the
+// source of the main module. The placeholders {revision}, {path} and
+// {line} are replaced with the VCS revision the binary was built from,
+// the file path relative to the module root and the line number, e.g.:
+//
+//	https://github.com/org/repo/blob/{revision}/{path}#L{line}
+var SourceURLTemplate = ""
+
+// SourceRoot is the directory of the main module's checkout at build
+// time. It is only needed for frames of package main built without
+// -trimpath; other frames locate the module root from their package path.
+var SourceRoot = ""
+
+var buildInfo struct {
+	once     sync.Once
+	module   string
+	revision string
+}
+
+func mainModule() (module, revision string) {
+	buildInfo.once.Do(func() {
+		buildInfo.revision = "HEAD"
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		buildInfo.module = info.Main.Path
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && setting.Value != "" {
+				buildInfo.revision = setting.Value
+			}
+		}
+	})
+	return buildInfo.module, buildInfo.revision
+}
+
+// SourceURL returns a link to the frame's line of source, built from
+// SourceURLTemplate, or "" if no template is set or the frame is not part
+// of the main module.
+func (frame *StackFrame) SourceURL() string {
+	if SourceURLTemplate == "" {
+		return ""
+	}
+	file := sourcePath(frame.File, frame.Package)
+	if file == "" {
+		return ""
+	}
+	_, revision := mainModule()
+	return strings.NewReplacer(
+		"{revision}", revision,
+		"{path}", file,
+		"{line}", strconv.Itoa(frame.LineNumber),
+	).Replace(SourceURLTemplate)
+}
+
+// sourcePath returns the path of file relative to the main module root.
+func sourcePath(file, pkg string) string {
+	module, _ := mainModule()
+
+	if module != "" && strings.HasPrefix(file, module+"/") {
+		return file[len(module)+1:] // built with -trimpath
+	}
+	if SourceRoot != "" && strings.HasPrefix(file, SourceRoot+"/") {
+		return file[len(SourceRoot)+1:]
+	}
+	if module == "" || (pkg != module && !strings.HasPrefix(pkg, module+"/")) {
+		return ""
+	}
+
+	dir := path.Dir(file)
+	rel := strings.TrimPrefix(pkg, module)
+	if !strings.HasSuffix(dir, rel) {
+		return ""
+	}
+	return strings.TrimPrefix(rel+"/"+path.Base(file), "/")
+}
@@ -2,11 +2,41 @@ package errgo
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 )
 
+// FrameFormatter, when set, is used by Stack() and StackTrace() to format
+// each stack frame instead of StackFrame.String().
+var FrameFormatter func(StackFrame) string
+
+// FrameTemplate returns a frame formatter, suitable for FrameFormatter,
+// that renders each frame with the given text/template. The template is
+// executed with the StackFrame as data, and may use the relative function
+// to shorten file paths, e.g.:
+//
+//	{{relative .File}}:{{.LineNumber}} {{.FunctionName}}
+func FrameTemplate(text string) (func(StackFrame) string, error) {
+	tmpl, err := template.New("frame").Funcs(template.FuncMap{
+		"relative": RelativeFilePath,
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(frame StackFrame) string {
+		buf := bytes.Buffer{}
+		if err := tmpl.Execute(&buf, frame); err != nil {
+			return frame.String()
+		}
+		return buf.String()
+	}, nil
+}
+
 // A StackFrame contains all necessary information about to generate a line
 // in a callstack.
 type StackFrame struct {
@@ -42,8 +72,134 @@ func (frame *StackFrame) String() string {
 	return fmt.Sprintf("%s: %s: line %d", RelativeFilePath(frame.File), frame.FunctionName, frame.LineNumber)
 }
 
+// stackFrameJSON is the wire format of a StackFrame.
+type stackFrameJSON struct {
+	File         string  `json:"file"`
+	LineNumber   int     `json:"line"`
+	FunctionName string  `json:"function"`
+	Package      string  `json:"package"`
+	Caller       uintptr `json:"pc,omitempty"`
+}
+
+// MarshalJSON encodes the frame as an object with the fields file, line,
+// function, package and pc. The pc field is omitted when it is unknown,
+// e.g. for frames read from a panic.
+func (frame StackFrame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stackFrameJSON{
+		File:         frame.File,
+		LineNumber:   frame.LineNumber,
+		FunctionName: frame.FunctionName,
+		Package:      frame.Package,
+		Caller:       frame.Caller,
+	})
+}
+
+// UnmarshalJSON decodes a frame in the format written by MarshalJSON.
+func (frame *StackFrame) UnmarshalJSON(data []byte) error {
+	var v stackFrameJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*frame = StackFrame{
+		Caller:       v.Caller,
+		File:         v.File,
+		LineNumber:   v.LineNumber,
+		FunctionName: v.FunctionName,
+		Package:      v.Package,
+	}
+	return nil
+}
+
+// ElideEntryFrames drops the frames of runtime.main, testing.tRunner and
+// goroutine entry points, along with everything below them, from resolved
+// stacks, since they appear in every trace and say nothing about the error.
+var ElideEntryFrames = true
+
+var entryFrames = map[string]bool{
+	"runtime.main":    true,
+	"runtime.goexit":  true,
+	"testing.tRunner": true,
+}
+
+// frameCache holds the resolved frames of a stack. It is shared between
+// copies of an error so the stack is only resolved once.
+type frameCache struct {
+	once   sync.Once
+	frames []StackFrame
+}
+
+func newFrameCache(frames []StackFrame) *frameCache {
+	return &frameCache{frames: frames}
+}
+
+func (cache *frameCache) get(stack []uintptr) []StackFrame {
+	cache.once.Do(func() {
+		if cache.frames == nil {
+			cache.frames = resolveFrames(stack)
+		}
+	})
+	return cache.frames
+}
+
+// resolveFrames turns program counters into frames. Unless
+// ElideEntryFrames is false, it stops at the first entry frame such as
+// runtime.main.
+//
+// It goes through runtime.CallersFrames rather than NewStackFrame for
+// each pc: a return address can fall inside code inlined into the caller,
+// and FuncForPC then names the inlined callee, so runtime.main shows up
+// as internal/runtime/atomic.(*Uint32).Load and is never elided.
+// CallersFrames attributes each pc to the right function, and also
+// expands inlined calls into their own frames.
+func resolveFrames(stack []uintptr) []StackFrame {
+	frames := make([]StackFrame, 0, len(stack))
+	if len(stack) == 0 {
+		return frames
+	}
+
+	iter := runtime.CallersFrames(stack)
+	for more := true; more; {
+		var f runtime.Frame
+		f, more = iter.Next()
+		frame := StackFrame{
+			Caller:     f.PC + 1,
+			File:       f.File,
+			LineNumber: f.Line,
+		}
+		frame.Package, frame.FunctionName = splitFunctionName(f.Function)
+		if ElideEntryFrames && entryFrames[f.Function] {
+			break
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// writeFrames writes one formatted frame per line, in reverse order if
+// StackReverse is set.
+func writeFrames(buf *bytes.Buffer, frames []StackFrame) {
+	for i := range frames {
+		frame := frames[i]
+		if StackReverse {
+			frame = frames[len(frames)-1-i]
+		}
+		buf.WriteString(formatFrame(frame))
+		buf.WriteString("\n")
+	}
+}
+
+func formatFrame(frame StackFrame) string {
+	if FrameFormatter != nil {
+		return FrameFormatter(frame)
+	}
+	return frame.String()
+}
+
 func packageAndName(fn *runtime.Func) (string, string) {
-	name := fn.Name()
+	return splitFunctionName(fn.Name())
+}
+
+func splitFunctionName(name string) (string, string) {
 	pkg := ""
 
 	// The name includes the path name to the package, which is unnecessary
@@ -67,24 +223,45 @@ func packageAndName(fn *runtime.Func) (string, string) {
 	return pkg, name
 }
 
+// relativePaths caches the results of RelativeFilePath, since the same
+// few files show up in almost every stack. It holds at most
+// maxRelativePaths entries, so paths from decoded or parsed traces, which
+// can be anything, cannot grow it without bound; further paths are
+// trimmed on every call.
+var relativePaths struct {
+	sync.Map
+	count atomic.Int64
+}
+
+const maxRelativePaths = 4096
+
 // RelativeFilePath removes absolute paths - basically cuts
 // out addresses at /src/ and before.
 func RelativeFilePath(file string) string {
-	folders := strings.Split(file, "/")
-	count := 0
-	for _, folder := range folders {
-		if folder == "src" {
-			break
+	if rel, ok := relativePaths.Load(file); ok {
+		return rel.(string)
+	}
+	rel := trimToSrc(file)
+	if relativePaths.count.Load() < maxRelativePaths {
+		if _, loaded := relativePaths.LoadOrStore(file, rel); !loaded {
+			relativePaths.count.Add(1)
 		}
-		count++
 	}
-	if count >= len(folders) {
-		return file
+	return rel
+}
+
+func trimToSrc(file string) string {
+	switch {
+	case file == "src":
+		return ""
+	case strings.HasPrefix(file, "src/"):
+		return file[len("src"):]
+	}
+	if idx := strings.Index(file, "/src/"); idx >= 0 {
+		return file[idx+len("/src"):]
 	}
-	buf := bytes.Buffer{}
-	for _, folder := range folders[count+1:] {
-		buf.WriteString("/")
-		buf.WriteString(folder)
+	if strings.HasSuffix(file, "/src") {
+		return ""
 	}
-	return buf.String()
+	return file
 }
@@ -0,0 +1,161 @@
+package errgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRelativeFilePath(t *testing.T) {
+	cases := []struct {
+		file     string
+		expected string
+	}{
+		{"/home/user/go/src/github.com/org/app/main.go", "/github.com/org/app/main.go"},
+		{"src/runtime/proc.go", "/runtime/proc.go"},
+		{"/usr/local/go/src", ""},
+		{"src", ""},
+		{"github.com/org/app/main.go", "github.com/org/app/main.go"},
+	}
+	for _, c := range cases {
+		if actual := RelativeFilePath(c.file); actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.file, c.expected, actual)
+		}
+	}
+}
+
+func TestRelativeFilePathCacheBounded(t *testing.T) {
+	for i := 0; i < maxRelativePaths+100; i++ {
+		file := fmt.Sprintf("/remote/src/pkg%d/file.go", i)
+		if rel := RelativeFilePath(file); rel != fmt.Sprintf("/pkg%d/file.go", i) {
+			t.Fatalf("%s: got %q", file, rel)
+		}
+	}
+	if n := relativePaths.count.Load(); n > maxRelativePaths {
+		t.Errorf("expected at most %d cached paths, got %d", maxRelativePaths, n)
+	}
+}
+
+func TestFrameTemplate(t *testing.T) {
+	frame := StackFrame{
+		File:         "/home/user/go/src/github.com/org/app/main.go",
+		LineNumber:   10,
+		FunctionName: "handler",
+		Package:      "github.com/org/app",
+	}
+
+	cases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"fields", "{{.Package}}.{{.FunctionName}}:{{.LineNumber}}", "github.com/org/app.handler:10"},
+		{"relative", "{{relative .File}}:{{.LineNumber}}", "/github.com/org/app/main.go:10"},
+		{"execution error", "{{.Missing}}", frame.String()},
+	}
+	for _, c := range cases {
+		format, err := FrameTemplate(c.text)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if actual := format(frame); actual != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, actual)
+		}
+	}
+
+	if _, err := FrameTemplate("{{.FunctionName"); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestFrameFormatter(t *testing.T) {
+	requireStacks(t)
+	defer func(f func(StackFrame) string) { FrameFormatter = f }(FrameFormatter)
+	FrameFormatter, _ = FrameTemplate("at {{.FunctionName}}")
+
+	stack := Wrap(errors.New("x")).Stack()
+	if !strings.HasPrefix(stack, "at TestFrameFormatter\n") {
+		t.Errorf("expected the formatter to be used, got:\n%s", stack)
+	}
+}
+
+func TestStackFrameJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		frame    StackFrame
+		expected string
+	}{
+		{
+			"with pc",
+			StackFrame{Caller: 0x1234, File: "/app/main.go", LineNumber: 10, FunctionName: "main", Package: "main"},
+			`{"file":"/app/main.go","line":10,"function":"main","package":"main","pc":4660}`,
+		},
+		{
+			"without pc",
+			StackFrame{File: "/app/main.go", LineNumber: 3, FunctionName: "(*T).Run", Package: "example.com/app"},
+			`{"file":"/app/main.go","line":3,"function":"(*T).Run","package":"example.com/app"}`,
+		},
+	}
+	for _, c := range cases {
+		data, err := json.Marshal(c.frame)
+		if err != nil || string(data) != c.expected {
+			t.Errorf("%s: expected %s, got %s (%v)", c.name, c.expected, data, err)
+			continue
+		}
+		var decoded StackFrame
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != c.frame {
+			t.Errorf("%s: expected %+v back, got %+v (%v)", c.name, c.frame, decoded, err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"line":"ten"}`), new(StackFrame)); err == nil {
+		t.Error("expected an error for a malformed frame")
+	}
+}
+
+func TestStackReverse(t *testing.T) {
+	defer func(reverse bool, f func(StackFrame) string) {
+		StackReverse, FrameFormatter = reverse, f
+	}(StackReverse, FrameFormatter)
+	FrameFormatter, _ = FrameTemplate("{{.FunctionName}}")
+
+	frames := []StackFrame{{FunctionName: "inner"}, {FunctionName: "middle"}, {FunctionName: "main"}}
+	cases := []struct {
+		reverse  bool
+		expected string
+	}{
+		{false, "inner\nmiddle\nmain\n"},
+		{true, "main\nmiddle\ninner\n"},
+	}
+	for _, c := range cases {
+		StackReverse = c.reverse
+		buf := bytes.Buffer{}
+		writeFrames(&buf, frames)
+		if actual := buf.String(); actual != c.expected {
+			t.Errorf("reverse %v: expected %q, got %q", c.reverse, c.expected, actual)
+		}
+	}
+}
+
+func TestStackReverseTrace(t *testing.T) {
+	requireStacks(t)
+	defer func(reverse bool) { StackReverse = reverse }(StackReverse)
+
+	err := Wrap(errors.New("x"))
+	StackReverse = false
+	forward := strings.Split(strings.TrimSuffix(err.Stack(), "\n"), "\n")
+	StackReverse = true
+	reversed := strings.Split(strings.TrimSuffix(err.Stack(), "\n"), "\n")
+
+	if len(forward) != len(reversed) {
+		t.Fatalf("expected %d frames, got %d", len(forward), len(reversed))
+	}
+	for i := range forward {
+		if forward[i] != reversed[len(reversed)-1-i] {
+			t.Errorf("frame %d: expected %q, got %q", i, forward[i], reversed[len(reversed)-1-i])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package errgo
+
+import "net/http"
+
+// HTTPStatus returns the HTTP status for err: the status registered for
+// its code, if any, and otherwise the status for its Kind.
+func HTTPStatus(err error) int {
+	if def, ok := registered(err, func(def Definition) bool { return def.HTTPStatus != 0 }); ok {
+		return def.HTTPStatus
+	}
+	return KindOf(err).HTTPStatus()
+}
+
+// HTTPStatus returns the HTTP status for errors of kind k: 400 for
+// Invalid, 403 for Permission, 409 for Exist, 404 for NotExist, 503 for
+// IO and Transient and 500 for everything else.
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case Invalid:
+		return http.StatusBadRequest
+	case Permission:
+		return http.StatusForbidden
+	case Exist:
+		return http.StatusConflict
+	case NotExist:
+		return http.StatusNotFound
+	case IO, Transient:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the numeric google.rpc.Code for err: the code
+// registered for its errgo code, if any, and otherwise the code for its
+// Kind.
+func GRPCCode(err error) int {
+	if def, ok := registered(err, func(def Definition) bool { return def.GRPCCode != 0 }); ok {
+		return def.GRPCCode
+	}
+	return KindOf(err).GRPCCode()
+}
+
+// GRPCCode returns the numeric google.rpc.Code for errors of kind k:
+// INVALID_ARGUMENT for Invalid, PERMISSION_DENIED for Permission,
+// ALREADY_EXISTS for Exist, NOT_FOUND for NotExist, UNAVAILABLE for IO and
+// Transient, INTERNAL for Internal and UNKNOWN for everything else.
+func (k Kind) GRPCCode() int {
+	switch k {
+	case Invalid:
+		return 3
+	case Permission:
+		return 7
+	case Exist:
+		return 6
+	case NotExist:
+		return 5
+	case IO, Transient:
+		return 14
+	case Internal:
+		return 13
+	}
+	return 2
+}
+
+// KindOfGRPCCode returns the Kind for errors received with the numeric
+// google.rpc.Code code, the reverse of Kind.GRPCCode: Invalid for
+// INVALID_ARGUMENT, FAILED_PRECONDITION and OUT_OF_RANGE, Permission for
+// PERMISSION_DENIED and UNAUTHENTICATED, Exist for ALREADY_EXISTS,
+// NotExist for NOT_FOUND, Transient for UNAVAILABLE, DEADLINE_EXCEEDED,
+// RESOURCE_EXHAUSTED and ABORTED, Internal for INTERNAL and DATA_LOSS, and
+// Other for everything else.
+func KindOfGRPCCode(code int) Kind {
+	switch code {
+	case 3, 9, 11:
+		return Invalid
+	case 7, 16:
+		return Permission
+	case 6:
+		return Exist
+	case 5:
+		return NotExist
+	case 4, 8, 10, 14:
+		return Transient
+	case 13, 15:
+		return Internal
+	}
+	return Other
+}
+
+// registered returns the definition of the first code in the chain of err
+// whose definition satisfies ok.
+func registered(err error, ok func(Definition) bool) (Definition, bool) {
+	errs, _ := walkChain(err)
+	for _, err := range errs {
+		if e, isStackable := err.(*StackableError); isStackable && e.Code != "" {
+			if def, found := Lookup(e.Code); found && ok(def) {
+				return def, true
+			}
+		}
+	}
+	return Definition{}, false
+}
@@ -0,0 +1,84 @@
+package errgo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var (
+	_ = Register(Definition{Code: "status_test.quota", Kind: Transient, HTTPStatus: 429})
+	_ = Register(Definition{Code: "status_test.unmapped", Kind: NotExist})
+	_ = Register(Definition{Code: "status_test.grpc", Kind: Invalid, GRPCCode: 9})
+)
+
+func TestHTTPStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"plain error", errors.New("boom"), 500},
+		{"kind", E("op", Invalid, nil), 400},
+		{"wrapped kind", fmt.Errorf("outer: %w", E("op", NotExist, nil)), 404},
+		{"IO", E("op", IO, nil), 503},
+		{"registered", NewCode("status_test.quota", nil), 429},
+		{"registered without status", NewCode("status_test.unmapped", nil), 404},
+		{"unregistered code", NewCode("status_test.missing", nil), 500},
+	}
+	for _, c := range cases {
+		if actual := HTTPStatus(c.err); actual != c.expected {
+			t.Errorf("%s: expected %d, got %d", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"plain error", errors.New("boom"), 2},
+		{"kind", E("op", Permission, nil), 7},
+		{"wrapped kind", fmt.Errorf("outer: %w", E("op", Transient, nil)), 14},
+		{"registered", NewCode("status_test.grpc", nil), 9},
+	}
+	for _, c := range cases {
+		if actual := GRPCCode(c.err); actual != c.expected {
+			t.Errorf("%s: expected %d, got %d", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestKindOfGRPCCode(t *testing.T) {
+	cases := []struct {
+		code     int
+		expected Kind
+	}{
+		{0, Other},
+		{2, Other},
+		{3, Invalid},
+		{9, Invalid},
+		{16, Permission},
+		{6, Exist},
+		{5, NotExist},
+		{4, Transient},
+		{14, Transient},
+		{15, Internal},
+	}
+	for _, c := range cases {
+		if actual := KindOfGRPCCode(c.code); actual != c.expected {
+			t.Errorf("code %d: expected %v, got %v", c.code, c.expected, actual)
+		}
+	}
+
+	for _, kind := range Kinds() {
+		if kind == IO {
+			continue // served as UNAVAILABLE, which is received as Transient
+		}
+		if actual := KindOfGRPCCode(kind.GRPCCode()); actual != kind {
+			t.Errorf("%v: expected its code to map back to it, got %v", kind, actual)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package errgo
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SlowConsumerPolicy decides what happens to a new error when a
+// subscriber's buffer is full.
+type SlowConsumerPolicy int
+
+const (
+	// DropNewest discards the new error.
+	DropNewest SlowConsumerPolicy = iota
+	// DropOldest discards the oldest buffered error to make room.
+	DropOldest
+	// Block makes the goroutine creating the error wait for room. It
+	// should only be used by consumers that are certain to keep up.
+	Block
+)
+
+// SubscribeOptions configure a Subscription. Zero fields take the
+// defaults noted below.
+type SubscribeOptions struct {
+	Buffer int                // errors buffered, default 64
+	Policy SlowConsumerPolicy // default DropNewest
+}
+
+// A Subscription receives every error created after it was opened, once
+// the processors installed with Use have run. Receivers must not modify
+// the errors.
+type Subscription struct {
+	// C delivers the errors. It is closed by Close.
+	C <-chan *StackableError
+
+	ch      chan *StackableError
+	policy  SlowConsumerPolicy
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+
+	// sending is held for reading by publishers while they send, and for
+	// writing by Close before it closes ch.
+	sending sync.RWMutex
+	closed  bool
+}
+
+var subscribers struct {
+	sync.RWMutex
+	subs  map[*Subscription]struct{}
+	count atomic.Int32
+}
+
+// Subscribe opens a Subscription, for diagnostics UIs and test harnesses
+// that want to observe errors as they are created.
+func Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+	ch := make(chan *StackableError, opts.Buffer)
+	s := &Subscription{C: ch, ch: ch, policy: opts.Policy, done: make(chan struct{})}
+
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	if subscribers.subs == nil {
+		subscribers.subs = map[*Subscription]struct{}{}
+	}
+	subscribers.subs[s] = struct{}{}
+	subscribers.count.Add(1)
+	return s
+}
+
+// SubscribeFunc opens a Subscription and calls fn with each error on a
+// goroutine of its own, until the subscription is closed.
+func SubscribeFunc(fn func(*StackableError), opts SubscribeOptions) *Subscription {
+	s := Subscribe(opts)
+	go func() {
+		for err := range s.C {
+			fn(err)
+		}
+	}()
+	return s
+}
+
+// Dropped returns the number of errors discarded because the buffer was
+// full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops the subscription and closes C. Errors still buffered can be
+// received after Close.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		subscribers.Lock()
+		delete(subscribers.subs, s)
+		subscribers.count.Add(-1)
+		subscribers.Unlock()
+
+		s.sending.Lock()
+		defer s.sending.Unlock()
+		s.closed = true
+		close(s.ch)
+	})
+}
+
+func (s *Subscription) send(err *StackableError) {
+	s.sending.RLock()
+	defer s.sending.RUnlock()
+	if s.closed {
+		return
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- err:
+		case <-s.done:
+		}
+		return
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- err:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	}
+	select {
+	case s.ch <- err:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// publish sends a new error to the subscribers. Pooled errors are copied,
+// since they may be released while a subscriber still holds them.
+func publish(err *StackableError) {
+	if subscribers.count.Load() == 0 {
+		return
+	}
+	if err.pooled {
+		cp := err.clone()
+		cp.stack = append([]uintptr(nil), err.stack...)
+		cp.frames = newFrameCache(nil)
+		err = cp
+	}
+
+	// Send outside the lock, so a blocked subscriber does not hold up
+	// Subscribe, Close or the creation of other errors.
+	subscribers.RLock()
+	subs := make([]*Subscription, 0, len(subscribers.subs))
+	for s := range subscribers.subs {
+		subs = append(subs, s)
+	}
+	subscribers.RUnlock()
+
+	for _, s := range subs {
+		s.send(err)
+	}
+}
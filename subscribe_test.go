@@ -0,0 +1,140 @@
+package errgo
+
+import (
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, s *Subscription) []string {
+	t.Helper()
+	var msgs []string
+	for {
+		select {
+		case err, ok := <-s.C:
+			if !ok {
+				return msgs
+			}
+			msgs = append(msgs, err.Error())
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestSubscribeDropPolicies(t *testing.T) {
+	cases := []struct {
+		policy   SlowConsumerPolicy
+		expected []string
+	}{
+		{DropNewest, []string{"1", "2"}},
+		{DropOldest, []string{"2", "3"}},
+	}
+
+	for _, c := range cases {
+		s := Subscribe(SubscribeOptions{Buffer: 2, Policy: c.policy})
+		Wrap("1")
+		Wrap("2")
+		Wrap("3")
+		s.Close()
+
+		if actual := receive(t, s); len(actual) != 2 || actual[0] != c.expected[0] || actual[1] != c.expected[1] {
+			t.Errorf("policy %d: expected %v, got %v", c.policy, c.expected, actual)
+		}
+		if s.Dropped() != 1 {
+			t.Errorf("policy %d: expected 1 dropped error, got %d", c.policy, s.Dropped())
+		}
+	}
+}
+
+func TestSubscribeBlock(t *testing.T) {
+	s := Subscribe(SubscribeOptions{Buffer: 1, Policy: Block})
+
+	done := make(chan struct{})
+	go func() {
+		Wrap("1")
+		Wrap("2")
+		Wrap("3")
+		close(done)
+	}()
+
+	for _, expected := range []string{"1", "2"} {
+		select {
+		case err := <-s.C:
+			if err.Error() != expected {
+				t.Errorf("expected %s, got %s", expected, err.Error())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+
+	// "3" fits in the buffer.
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("creating an error stayed blocked")
+	}
+	blocked := make(chan struct{})
+	go func() {
+		Wrap("4")
+		close(blocked)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	select {
+	case <-blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not release a blocked creator")
+	}
+	if s.Dropped() != 0 {
+		t.Errorf("expected no dropped errors, got %d", s.Dropped())
+	}
+}
+
+func TestSubscribePooledErrorsCopied(t *testing.T) {
+	requireStacks(t)
+	s := Subscribe(SubscribeOptions{})
+	defer s.Close()
+
+	err := Acquire("pooled")
+	received := <-s.C
+	if received == err {
+		t.Fatal("a pooled error was published without being copied")
+	}
+	Release(err)
+	if received.Error() != "pooled" || len(received.Callers()) == 0 {
+		t.Errorf("the published copy changed after Release: %q", received.Error())
+	}
+}
+
+func TestSubscribeBlockDoesNotHoldLock(t *testing.T) {
+	slow := Subscribe(SubscribeOptions{Buffer: 1, Policy: Block})
+	defer slow.Close()
+	Wrap("fills the buffer")
+
+	blocked := make(chan struct{})
+	go func() {
+		Wrap("blocks")
+		close(blocked)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		other := Subscribe(SubscribeOptions{})
+		other.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe and Close waited for a blocked subscriber")
+	}
+
+	<-slow.C
+	select {
+	case <-blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the blocked creator was not released")
+	}
+}
@@ -0,0 +1,123 @@
+package errgo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Suppressor folds repeated errors with the same fingerprint into one log
+// line per window, so an error storm does not flood the logs.
+type Suppressor struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*suppressed
+	pruned  time.Time
+	flush   func(err error, repeated int)
+}
+
+type suppressed struct {
+	logged time.Time // when the error was last allowed through
+	count  int       // occurrences suppressed since then
+	last   error     // the latest suppressed occurrence
+}
+
+// NewSuppressor returns a Suppressor that lets each distinct error through
+// at most once per window.
+func NewSuppressor(window time.Duration) *Suppressor {
+	return &Suppressor{window: window, entries: map[string]*suppressed{}}
+}
+
+// OnFlush sets a function to call with the latest suppressed occurrence
+// and the number of suppressed repeats of an error whose window ended
+// without it being logged again, just before the Suppressor forgets it.
+// Without one, those counts are dropped.
+func (s *Suppressor) OnFlush(fn func(err error, repeated int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush = fn
+}
+
+// Allow reports whether err should be logged now. If it should, it also
+// returns how many identical errors were suppressed since it was last
+// logged, so the log line can say "repeated N times".
+func (s *Suppressor) Allow(err error) (bool, int) {
+	key := suppressKey(err)
+	t := now()
+
+	s.mu.Lock()
+	allowed, count := s.allow(key, err, t)
+	expired := s.prune(t)
+	flush := s.flush
+	s.mu.Unlock()
+
+	if flush != nil {
+		for _, entry := range expired {
+			flush(entry.last, entry.count)
+		}
+	}
+	return allowed, count
+}
+
+func (s *Suppressor) allow(key string, err error, t time.Time) (bool, int) {
+	entry := s.entries[key]
+	if entry == nil {
+		s.entries[key] = &suppressed{logged: t}
+		return true, 0
+	}
+	if t.Sub(entry.logged) < s.window {
+		entry.count++
+		entry.last = err
+		return false, 0
+	}
+
+	count := entry.count
+	entry.logged = t
+	entry.count = 0
+	entry.last = nil
+	return true, count
+}
+
+// Log logs err like the package-level Log, unless Allow suppresses it. The
+// number of suppressed repeats, if any, is added as the "repeated"
+// attribute.
+func (s *Suppressor) Log(ctx context.Context, logger *slog.Logger, err error) {
+	ok, count := s.Allow(err)
+	if !ok {
+		return
+	}
+	if count > 0 {
+		logger = logger.With(slog.Int("repeated", count), slog.Duration("window", s.window))
+	}
+	Log(ctx, logger, err)
+}
+
+// prune forgets errors whose window has ended, and returns those that
+// had suppressed repeats so their counts can be flushed.
+func (s *Suppressor) prune(t time.Time) []*suppressed {
+	if t.Sub(s.pruned) < s.window {
+		return nil
+	}
+	s.pruned = t
+	var expired []*suppressed
+	for key, entry := range s.entries {
+		if t.Sub(entry.logged) < s.window {
+			continue
+		}
+		if entry.count > 0 {
+			expired = append(expired, entry)
+		}
+		delete(s.entries, key)
+	}
+	return expired
+}
+
+func suppressKey(err error) string {
+	if e, ok := err.(*StackableError); ok {
+		return e.Fingerprint()
+	}
+	return fmt.Sprintf("%T: %s", err, err.Error())
+}
@@ -0,0 +1,53 @@
+package errgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuppressor(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := start
+	SetNow(func() time.Time { return clock })
+	defer SetNow(nil)
+	at := func(d time.Duration) { clock = start.Add(d) }
+
+	type flushed struct {
+		err      error
+		repeated int
+	}
+	var flushes []flushed
+	s := NewSuppressor(time.Minute)
+	s.OnFlush(func(err error, repeated int) { flushes = append(flushes, flushed{err, repeated}) })
+
+	a, b, c := errors.New("a"), errors.New("b"), errors.New("c")
+	steps := []struct {
+		at       time.Duration
+		err      error
+		allowed  bool
+		repeated int
+	}{
+		{0, a, true, 0},
+		{time.Second, a, false, 0},
+		{2 * time.Second, b, true, 0},
+		{3 * time.Second, b, false, 0},
+		{4 * time.Second, b, false, 0},
+		{61 * time.Second, a, true, 1},
+		{3 * time.Minute, c, true, 0},
+	}
+	for i, step := range steps {
+		at(step.at)
+		allowed, repeated := s.Allow(step.err)
+		if allowed != step.allowed || repeated != step.repeated {
+			t.Errorf("step %d: expected (%v, %d), got (%v, %d)", i, step.allowed, step.repeated, allowed, repeated)
+		}
+	}
+
+	if len(flushes) != 1 || flushes[0].err != b || flushes[0].repeated != 2 {
+		t.Errorf("expected b to be flushed with 2 repeats, got %v", flushes)
+	}
+	if len(s.entries) != 1 {
+		t.Errorf("expected only c to be remembered, got %d entries", len(s.entries))
+	}
+}
@@ -0,0 +1,65 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutError is returned by RunTimeout when the operation ran out of
+// time.
+type TimeoutError struct {
+	Op      string
+	Limit   time.Duration
+	Elapsed time.Duration
+	Err     error
+}
+
+// Error describes the operation, the time it took and the limit.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s (limit %s): %s", e.Op, e.Elapsed.Round(time.Millisecond), e.Limit, e.Err.Error())
+}
+
+// Unwrap returns the error the operation failed with, usually
+// context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports true, for compatibility with net.Error.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// RunTimeout runs fn with a context that expires after d. If fn fails
+// because a deadline was exceeded, the error is returned as a
+// StackableError of kind Transient holding a *TimeoutError, named after
+// the function that called RunTimeout and with its stack. If ctx has a
+// deadline no later than d from now, it is the caller's limit rather than
+// d that runs out, so the error is returned unchanged. fn is expected to
+// return once its context is done.
+func RunTimeout(ctx context.Context, d time.Duration, fn func(ctx context.Context) error) error {
+	// The context package measures deadlines with time.Now, not with the
+	// clock set by SetNow.
+	deadline, ok := ctx.Deadline()
+	parentFirst := ok && time.Until(deadline) <= d
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	start := now()
+	err := fn(ctx)
+	if err == nil || parentFirst || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	e := newStackableError(nil, 1)
+	op := "operation"
+	if frames := e.StackFrames(); len(frames) > 0 {
+		op = frames[0].FunctionName
+	}
+	e.Err = &TimeoutError{Op: op, Limit: d, Elapsed: now().Sub(start), Err: err}
+	e.Kind = Transient
+	return created(e)
+}
@@ -0,0 +1,56 @@
+package errgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunTimeout(t *testing.T) {
+	waitForDeadline := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	other := errors.New("failed")
+
+	cases := []struct {
+		name    string
+		parent  time.Duration // deadline of the parent context, if any
+		fn      func(ctx context.Context) error
+		timeout bool
+		err     error
+	}{
+		{"success", 0, func(context.Context) error { return nil }, false, nil},
+		{"other error", 0, func(context.Context) error { return other }, false, other},
+		{"own limit", 0, waitForDeadline, true, context.DeadlineExceeded},
+		{"parent later", time.Hour, waitForDeadline, true, context.DeadlineExceeded},
+		{"parent first", 5 * time.Millisecond, waitForDeadline, false, context.DeadlineExceeded},
+	}
+	for _, c := range cases {
+		ctx := context.Background()
+		if c.parent > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.parent)
+			defer cancel()
+		}
+
+		err := RunTimeout(ctx, 20*time.Millisecond, c.fn)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+		var timeout *TimeoutError
+		if errors.As(err, &timeout) != c.timeout {
+			t.Errorf("%s: expected a TimeoutError: %v, got %v", c.name, c.timeout, err)
+			continue
+		}
+		if c.timeout {
+			if timeout.Limit != 20*time.Millisecond {
+				t.Errorf("%s: expected limit 20ms, got %v", c.name, timeout.Limit)
+			}
+			if KindOf(err) != Transient {
+				t.Errorf("%s: expected kind Transient, got %v", c.name, KindOf(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !tinygo
+
+package errgo
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+func traceEvent(err *StackableError) {
+	if trace.IsEnabled() {
+		trace.Log(context.Background(), "errgo", err.Fingerprint()+" "+err.Error())
+	}
+}
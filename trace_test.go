@@ -0,0 +1,36 @@
+//go:build !tinygo
+
+package errgo
+
+import (
+	"bytes"
+	"errors"
+	"runtime/trace"
+	"testing"
+)
+
+func TestTraceEvents(t *testing.T) {
+	defer func(enabled bool) { TraceEvents = enabled }(TraceEvents)
+
+	cases := []struct {
+		name    string
+		enabled bool
+		msg     string
+	}{
+		{"enabled", true, "trace_test enabled"},
+		{"disabled", false, "trace_test disabled"},
+	}
+	for _, c := range cases {
+		TraceEvents = c.enabled
+		var buf bytes.Buffer
+		if err := trace.Start(&buf); err != nil {
+			t.Skipf("execution tracer unavailable: %v", err)
+		}
+		err := Wrap(errors.New(c.msg))
+		trace.Stop()
+
+		if logged := bytes.Contains(buf.Bytes(), []byte(err.Fingerprint()+" "+c.msg)); logged != c.enabled {
+			t.Errorf("%s: expected the event to be logged: %v, got %v", c.name, c.enabled, logged)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build tinygo
+
+package errgo
+
+// traceEvent does nothing under TinyGo, which has no execution tracer.
+func traceEvent(err *StackableError) {}
@@ -0,0 +1,36 @@
+package errgo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// typedNilError replaces an error interface holding a nil pointer (or
+// other nil value) when it is wrapped, so the message points at the bug
+// instead of reading "<nil>".
+type typedNilError struct {
+	err error
+}
+
+func (e typedNilError) Error() string {
+	return fmt.Sprintf("<nil> (typed nil error of type %T)", e.err)
+}
+
+func (e typedNilError) Unwrap() error {
+	return e.err
+}
+
+// IsTypedNil reports whether err is a non-nil error interface holding a
+// nil value, such as a nil *MyError. Such errors compare unequal to nil
+// and are usually returned by mistake.
+func IsTypedNil(err error) bool {
+	if err == nil {
+		return false
+	}
+	v := reflect.ValueOf(err)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package errgo
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+type typedNilTestError struct{}
+
+func (*typedNilTestError) Error() string { return "never" }
+
+type funcError func()
+
+func (funcError) Error() string { return "func" }
+
+func TestIsTypedNil(t *testing.T) {
+	var ptr *typedNilTestError
+	var pathErr *os.PathError
+	var fn funcError
+	var stackable *StackableError
+
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"value", errors.New("x"), false},
+		{"non-nil pointer", &typedNilTestError{}, false},
+		{"nil pointer", ptr, true},
+		{"nil standard library pointer", pathErr, true},
+		{"nil func", fn, true},
+		{"nil StackableError", stackable, true},
+	}
+	for _, c := range cases {
+		if actual := IsTypedNil(c.err); actual != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestWrapTypedNil(t *testing.T) {
+	var ptr *typedNilTestError
+	var stackable *StackableError
+
+	cases := []struct {
+		name     string
+		err      *StackableError
+		typeName string
+	}{
+		{"Wrap", Wrap(error(ptr)), "*errgo.typedNilTestError"},
+		{"WrapPrefix", WrapPrefix(error(ptr), "load"), "*errgo.typedNilTestError"},
+		{"nil StackableError", Wrap(stackable), "*errgo.StackableError"},
+	}
+	for _, c := range cases {
+		if !strings.Contains(c.err.Error(), "<nil> (typed nil error of type "+c.typeName+")") {
+			t.Errorf("%s: expected the message to name %s, got %q", c.name, c.typeName, c.err.Error())
+		}
+	}
+
+	if !errors.Is(Wrap(error(ptr)), error(ptr)) {
+		t.Error("expected the typed nil to stay in the chain")
+	}
+}
@@ -0,0 +1,87 @@
+package errgo
+
+import (
+	"errors"
+	"strings"
+)
+
+// A FieldViolation describes one invalid field of a request.
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the fields that failed validation. It is usually
+// carried inside a StackableError of kind Invalid, made with Validation
+// or Err, and encodes to JSON as {"violations": [...]}.
+type ValidationError struct {
+	Violations []FieldViolation `json:"violations"`
+}
+
+// Error lists the violations as "field: message" pairs.
+func (v *ValidationError) Error() string {
+	msgs := make([]string, len(v.Violations))
+	for i, violation := range v.Violations {
+		msgs[i] = violation.Field + ": " + violation.Message
+	}
+	return "invalid input: " + strings.Join(msgs, "; ")
+}
+
+// Add records a violation for the field at the given path.
+func (v *ValidationError) Add(field, code, message string) {
+	v.Violations = append(v.Violations, FieldViolation{Field: field, Code: code, Message: message})
+}
+
+// Merge adds the violations of err, typically returned by validating a
+// nested value, with prefix and a dot prepended to their field paths. If
+// err holds no ValidationError, its message is recorded as a violation of
+// the prefix field itself. A nil err is ignored.
+func (v *ValidationError) Merge(prefix string, err error) {
+	if err == nil {
+		return
+	}
+
+	other, ok := AsValidationError(err)
+	if !ok {
+		v.Add(prefix, "", err.Error())
+		return
+	}
+
+	for _, violation := range other.Violations {
+		if prefix != "" {
+			if violation.Field == "" {
+				violation.Field = prefix
+			} else {
+				violation.Field = prefix + "." + violation.Field
+			}
+		}
+		v.Violations = append(v.Violations, violation)
+	}
+}
+
+// Err returns nil if there are no violations, and otherwise a
+// StackableError of kind Invalid carrying v and the caller's stack.
+func (v *ValidationError) Err() error {
+	if len(v.Violations) == 0 {
+		return nil
+	}
+	e := newStackableError(v, 1)
+	e.Kind = Invalid
+	return created(e)
+}
+
+// Validation makes a StackableError of kind Invalid from the given
+// violations.
+func Validation(violations ...FieldViolation) *StackableError {
+	e := newStackableError(&ValidationError{Violations: violations}, 1)
+	e.Kind = Invalid
+	return created(e)
+}
+
+// AsValidationError returns the ValidationError in the chain of err.
+func AsValidationError(err error) (*ValidationError, bool) {
+	var v *ValidationError
+	ok := errors.As(err, &v)
+	return v, ok
+}
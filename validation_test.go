@@ -0,0 +1,101 @@
+package errgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidationMerge(t *testing.T) {
+	nested := &ValidationError{}
+	nested.Add("street", "required", "is required")
+	nested.Add("", "", "is incomplete")
+
+	cases := []struct {
+		name     string
+		prefix   string
+		err      error
+		expected []FieldViolation
+	}{
+		{"nil", "address", nil, nil},
+		{"plain error", "address", errors.New("unparsable"), []FieldViolation{{Field: "address", Message: "unparsable"}}},
+		{
+			"nested",
+			"address",
+			nested.Err(),
+			[]FieldViolation{
+				{Field: "address.street", Code: "required", Message: "is required"},
+				{Field: "address", Message: "is incomplete"},
+			},
+		},
+		{
+			"no prefix",
+			"",
+			nested,
+			[]FieldViolation{
+				{Field: "street", Code: "required", Message: "is required"},
+				{Field: "", Message: "is incomplete"},
+			},
+		},
+		{
+			"wrapped",
+			"billing",
+			fmt.Errorf("decode: %w", Validation(FieldViolation{Field: "zip", Message: "too short"})),
+			[]FieldViolation{{Field: "billing.zip", Message: "too short"}},
+		},
+	}
+	for _, c := range cases {
+		v := &ValidationError{}
+		v.Merge(c.prefix, c.err)
+		if !reflect.DeepEqual(v.Violations, c.expected) {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.expected, v.Violations)
+		}
+	}
+}
+
+func TestValidationErr(t *testing.T) {
+	if err := (&ValidationError{}).Err(); err != nil {
+		t.Errorf("expected no error without violations, got %v", err)
+	}
+
+	v := &ValidationError{}
+	v.Add("name", "required", "is required")
+	v.Add("age", "range", "must be positive")
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"Err", v.Err()},
+		{"Validation", Validation(v.Violations...)},
+	}
+	for _, c := range cases {
+		if expected := "invalid input: name: is required; age: must be positive"; c.err.Error() != expected {
+			t.Errorf("%s: expected %q, got %q", c.name, expected, c.err.Error())
+		}
+		if KindOf(c.err) != Invalid {
+			t.Errorf("%s: expected kind Invalid, got %v", c.name, KindOf(c.err))
+		}
+		actual, ok := AsValidationError(c.err)
+		if !ok || !reflect.DeepEqual(actual.Violations, v.Violations) {
+			t.Errorf("%s: expected the violations to be found, got %+v", c.name, actual)
+		}
+	}
+}
+
+func TestValidationJSON(t *testing.T) {
+	v := &ValidationError{}
+	v.Add("name", "required", "is required")
+	v.Add("age", "", "must be positive")
+
+	actual, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"violations":[{"field":"name","code":"required","message":"is required"},{"field":"age","message":"must be positive"}]}`
+	if string(actual) != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}